@@ -0,0 +1,284 @@
+package gui
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	escapeSymbol byte = 0x1B
+	bellSymbol   byte = 0x07
+	deleteSymbol byte = 0x7F
+
+	tabWidth int = 8
+)
+
+// ParseANSI разбирает вывод с SGR-последовательностями (ESC [ ... m) в куски с начертанием, по строкам.
+// Поддерживаются 16, 256 и 24-битные цвета и атрибуты. Прочие escape-последовательности и управляющие символы
+// отбрасываются, табуляция разворачивается в пробелы до ближайшего столбца, кратного 8.
+// Начертание переносится между строками так же, как в терминале. Код 0 возвращает начертание base
+func ParseANSI(text string, base StyledRun) [][]StyledRun {
+	lines := make([][]StyledRun, 0)
+	line := make([]StyledRun, 0)
+	lineWidth := 0
+
+	style := base
+	chunk := strings.Builder{}
+	flushChunk := func() {
+		if chunk.Len() == 0 {
+			return
+		}
+
+		run := style
+		run.Text = chunk.String()
+		line = append(line, run)
+		lineWidth += TextWidth(run.Text)
+
+		chunk.Reset()
+	}
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case escapeSymbol:
+			flushChunk()
+
+			var sequenceLength int
+			style, sequenceLength = parseEscapeSequence(text[i:], style, base)
+			i += sequenceLength - 1
+		case '\n':
+			flushChunk()
+
+			lines = append(lines, line)
+			line = make([]StyledRun, 0)
+			lineWidth = 0
+		case '\t':
+			flushChunk()
+
+			spaces := tabWidth - lineWidth%tabWidth
+			chunk.WriteString(strings.Repeat(" ", spaces))
+			flushChunk()
+		default:
+			// '\r', BEL, BS и прочие управляющие символы не занимают клеток
+			if text[i] < ' ' || text[i] == deleteSymbol {
+				continue
+			}
+
+			chunk.WriteByte(text[i])
+		}
+	}
+
+	flushChunk()
+
+	// перевод строки в конце вывода не порождает пустую строку
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// parseEscapeSequence возвращает новое начертание и длину последовательности в байтах
+func parseEscapeSequence(text string, style, base StyledRun) (StyledRun, int) {
+	if len(text) < 2 {
+		return style, len(text)
+	}
+
+	switch text[1] {
+	case '[':
+		// CSI: параметры 0x30-0x3F, промежуточные 0x20-0x2F, финальный байт 0x40-0x7E
+		for i := 2; i < len(text); i++ {
+			if text[i] >= 0x40 && text[i] <= 0x7E {
+				if text[i] == 'm' {
+					style = applySGR(text[2:i], style, base)
+				}
+
+				return style, i + 1
+			}
+		}
+
+		return style, len(text)
+	case ']', 'P', 'X', '^', '_':
+		// OSC, DCS, SOS, PM и APC заканчиваются ESC \, OSC - ещё и BEL
+		for i := 2; i < len(text); i++ {
+			if text[i] == bellSymbol && text[1] == ']' {
+				return style, i + 1
+			}
+
+			if text[i] == escapeSymbol && i+1 < len(text) && text[i+1] == '\\' {
+				return style, i + 2
+			}
+		}
+
+		return style, len(text)
+	default:
+		// nF и Fp/Fe/Fs: промежуточные байты 0x20-0x2F, затем один финальный, например ESC ( B
+		i := 1
+		for i < len(text) && text[i] >= 0x20 && text[i] <= 0x2F {
+			i++
+		}
+
+		return style, min(i+1, len(text))
+	}
+}
+
+func applySGR(parameters string, style, base StyledRun) StyledRun {
+	if parameters == "" {
+		return base
+	}
+
+	groups := strings.Split(parameters, ";")
+	for i := 0; i < len(groups); i++ {
+		// форма с двоеточиями (38:2::r:g:b) целиком лежит в одной группе
+		if strings.Contains(groups[i], ":") {
+			style = applyExtendedColor(splitSGRParameters(groups[i], ":"), style)
+			continue
+		}
+
+		code := parseSGRParameter(groups[i])
+
+		switch {
+		case code == 0:
+			style = base
+		case code == 1:
+			style.Attributes |= AttrBold
+		case code == 2:
+			style.Attributes |= AttrDim
+		case code == 3:
+			style.Attributes |= AttrItalic
+		case code == 4:
+			style.Attributes |= AttrUnderline
+		case code == 5 || code == 6:
+			style.Attributes |= AttrBlink
+		case code == 7:
+			style.Attributes |= AttrReverse
+		case code == 9:
+			style.Attributes |= AttrStrikethrough
+		case code == 22:
+			style.Attributes &^= AttrBold | AttrDim
+		case code == 23:
+			style.Attributes &^= AttrItalic
+		case code == 24:
+			style.Attributes &^= AttrUnderline
+		case code == 25:
+			style.Attributes &^= AttrBlink
+		case code == 27:
+			style.Attributes &^= AttrReverse
+		case code == 29:
+			style.Attributes &^= AttrStrikethrough
+		case code >= 30 && code <= 37:
+			style.Foreground = PaletteColor(code - 30)
+		case code == 38 || code == 48:
+			extendedLength := extendedColorLength(groups[i:])
+			style = applyExtendedColor(splitSGRParameters(strings.Join(groups[i:i+extendedLength], ";"), ";"), style)
+			i += extendedLength - 1
+		case code == 39:
+			style.Foreground = base.Foreground
+		case code >= 40 && code <= 47:
+			style.Background = PaletteColor(code - 40)
+		case code == 49:
+			style.Background = base.Background
+		case code >= 90 && code <= 97:
+			style.Foreground = PaletteColor(code - 90 + 8)
+		case code >= 100 && code <= 107:
+			style.Background = PaletteColor(code - 100 + 8)
+		}
+	}
+
+	return style
+}
+
+// extendedColorLength возвращает, сколько групп занимает 38;5;n или 38;2;r;g;b
+func extendedColorLength(groups []string) int {
+	if len(groups) < 2 {
+		return len(groups)
+	}
+
+	switch parseSGRParameter(groups[1]) {
+	case 5:
+		return min(3, len(groups))
+	case 2:
+		return min(5, len(groups))
+	default:
+		return 2
+	}
+}
+
+// applyExtendedColor применяет 38/48 в виде списка чисел: [38 5 n] или [38 2 r g b] (или [38 2 cs r g b])
+func applyExtendedColor(parameters []int, style StyledRun) StyledRun {
+	if len(parameters) < 3 {
+		return style
+	}
+
+	var color Color
+	switch parameters[1] {
+	case 5:
+		color = PaletteColor(parameters[2])
+	case 2:
+		rgb := parameters[2:]
+		if len(rgb) > 3 {
+			rgb = rgb[len(rgb)-3:]
+		}
+		if len(rgb) != 3 {
+			return style
+		}
+
+		color = Color{
+			R: min(max(rgb[0], 0), 255),
+			G: min(max(rgb[1], 0), 255),
+			B: min(max(rgb[2], 0), 255),
+		}
+	default:
+		return style
+	}
+
+	switch parameters[0] {
+	case 38:
+		style.Foreground = color
+	case 48:
+		style.Background = color
+	}
+
+	return style
+}
+
+func splitSGRParameters(text, separator string) []int {
+	groups := strings.Split(text, separator)
+
+	parameters := make([]int, 0, len(groups))
+	for i := range groups {
+		parameters = append(parameters, parseSGRParameter(groups[i]))
+	}
+
+	return parameters
+}
+
+// пустой параметр равен нулю
+func parseSGRParameter(text string) int {
+	parameter, err := strconv.Atoi(text)
+	if err != nil {
+		return 0
+	}
+
+	return parameter
+}
+
+// draw
+
+// SetANSI рисует вывод с SGR-последовательностями (см. ParseANSI) начиная с (x, y), каждую строку с новой y.
+// foreground и background задают начертание по умолчанию. Возвращает ширину самой длинной строки и число строк
+func (ctx *Context) SetANSI(x, y int, text string, foreground, background Color) (int, int) {
+	base := StyledRun{
+		Foreground: foreground,
+		Background: background,
+	}
+
+	lines := ParseANSI(text, base)
+
+	width := 0
+	for i := range lines {
+		lineWidth := ctx.SetStyledRuns(x, y+i, lines[i])
+		width = max(width, lineWidth)
+	}
+
+	return width, len(lines)
+}
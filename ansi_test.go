@@ -0,0 +1,121 @@
+package gui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseANSI(t *testing.T) {
+	base := StyledRun{
+		Foreground: DefaultColor,
+		Background: DefaultColor,
+	}
+
+	styled := func(text string, foreground, background Color, attributes Attribute) StyledRun {
+		return StyledRun{Text: text, Foreground: foreground, Background: background, Attributes: attributes}
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want [][]StyledRun
+	}{
+		{
+			name: "plain",
+			text: "plain",
+			want: [][]StyledRun{{styled("plain", DefaultColor, DefaultColor, NoAttributes)}},
+		},
+		{
+			name: "16 colors",
+			text: "\x1b[31ma\x1b[42mb\x1b[93;104mc",
+			want: [][]StyledRun{{
+				styled("a", PaletteColor(1), DefaultColor, NoAttributes),
+				styled("b", PaletteColor(1), PaletteColor(2), NoAttributes),
+				styled("c", PaletteColor(11), PaletteColor(12), NoAttributes),
+			}},
+		},
+		{
+			name: "256 colors",
+			text: "\x1b[38;5;196;48;5;21mx",
+			want: [][]StyledRun{{styled("x", PaletteColor(196), PaletteColor(21), NoAttributes)}},
+		},
+		{
+			name: "24-bit",
+			text: "\x1b[38;2;10;20;30;48;2;300;0;1mx",
+			want: [][]StyledRun{{styled("x", Color{R: 10, G: 20, B: 30}, Color{R: 255, G: 0, B: 1}, NoAttributes)}},
+		},
+		{
+			name: "colon form",
+			text: "\x1b[38:2::1:2:3;48:5:9mx\x1b[38:2:4:5:6my",
+			want: [][]StyledRun{{
+				styled("x", Color{R: 1, G: 2, B: 3}, PaletteColor(9), NoAttributes),
+				styled("y", Color{R: 4, G: 5, B: 6}, PaletteColor(9), NoAttributes),
+			}},
+		},
+		{
+			name: "attributes and partial resets",
+			text: "\x1b[1;3;4mx\x1b[22;24my\x1b[39;49;23mz",
+			want: [][]StyledRun{{
+				styled("x", DefaultColor, DefaultColor, AttrBold|AttrItalic|AttrUnderline),
+				styled("y", DefaultColor, DefaultColor, AttrItalic),
+				styled("z", DefaultColor, DefaultColor, NoAttributes),
+			}},
+		},
+		{
+			name: "reset",
+			text: "\x1b[1;31mx\x1b[0my\x1b[31mz\x1b[mw",
+			want: [][]StyledRun{{
+				styled("x", PaletteColor(1), DefaultColor, AttrBold),
+				styled("y", DefaultColor, DefaultColor, NoAttributes),
+				styled("z", PaletteColor(1), DefaultColor, NoAttributes),
+				styled("w", DefaultColor, DefaultColor, NoAttributes),
+			}},
+		},
+		{
+			name: "tput sgr0",
+			text: "\x1b[31mx\x1b(B\x1b[my",
+			want: [][]StyledRun{{
+				styled("x", PaletteColor(1), DefaultColor, NoAttributes),
+				styled("y", DefaultColor, DefaultColor, NoAttributes),
+			}},
+		},
+		{
+			name: "other escapes",
+			text: "a\x1b]0;title\x07b\x1b[2Kc\x1b=d\x1b7e\x1bPq#0~\x1b\\f",
+			want: [][]StyledRun{{
+				styled("a", DefaultColor, DefaultColor, NoAttributes),
+				styled("b", DefaultColor, DefaultColor, NoAttributes),
+				styled("c", DefaultColor, DefaultColor, NoAttributes),
+				styled("d", DefaultColor, DefaultColor, NoAttributes),
+				styled("e", DefaultColor, DefaultColor, NoAttributes),
+				styled("f", DefaultColor, DefaultColor, NoAttributes),
+			}},
+		},
+		{
+			name: "control characters",
+			text: "a\bb\x07c\r\x7fd",
+			want: [][]StyledRun{{styled("abcd", DefaultColor, DefaultColor, NoAttributes)}},
+		},
+		{
+			name: "style carries over lines",
+			text: "\x1b[32mx\nab\tc\n",
+			want: [][]StyledRun{
+				{styled("x", PaletteColor(2), DefaultColor, NoAttributes)},
+				{
+					styled("ab", PaletteColor(2), DefaultColor, NoAttributes),
+					styled("      ", PaletteColor(2), DefaultColor, NoAttributes),
+					styled("c", PaletteColor(2), DefaultColor, NoAttributes),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ParseANSI(test.text, base)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ParseANSI(%q)\ngot  %+v\nwant %+v", test.text, got, test.want)
+			}
+		})
+	}
+}
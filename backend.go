@@ -0,0 +1,61 @@
+package gui
+
+import (
+	"os"
+	"strings"
+)
+
+type (
+	// Backend - терминальный движок, через который Screen и Context рисуют и получают события.
+	// Координаты в SetCell и SetCursor экранные, смещение видимой области уже учтено
+	Backend interface {
+		Init() error
+		Close()
+		Size() (int, int)
+		SetCell(x, y int, cell Cell)
+		Clear(cell Cell) error
+		Flush() error
+		// Sync выводит весь экран заново, даже если бэкенд считает, что на терминале уже то же самое.
+		// Нужен, когда экран испортил другой процесс
+		Sync() error
+		// SyncRows выводит заново height строк экрана начиная с y, тоже не сравнивая их с уже выведенным.
+		// Так стираются картинки Sixel: терминал убирает их точки только под заново выведенными клетками
+		SyncRows(y, height int) error
+		// PollEvent блокируется до следующего события. nil означает, что бэкенд закрыт
+		PollEvent() Event
+		SetCursor(x, y int)
+		HideCursor()
+		SetOutputMode(outputMode OutputMode)
+	}
+
+	OutputMode int
+)
+
+const (
+	// OutputAuto выбирает режим по переменным окружения, см. DetectOutputMode. Бэкенду не передаётся
+	OutputAuto OutputMode = iota
+	Output8
+	Output16
+	Output256
+	OutputRGB
+)
+
+// DetectOutputMode определяет поддерживаемые терминалом цвета по COLORTERM и TERM
+func DetectOutputMode() OutputMode {
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+	if colorTerm == "truecolor" || colorTerm == "24bit" {
+		return OutputRGB
+	}
+
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case strings.Contains(term, "truecolor") || strings.Contains(term, "direct"):
+		return OutputRGB
+	case strings.Contains(term, "256color"):
+		return Output256
+	case term == "" || term == "dumb" || term == "linux" || term == "vt100" || term == "cons25":
+		return Output8
+	default:
+		return Output16
+	}
+}
@@ -0,0 +1,212 @@
+package gui
+
+type (
+	// CellBuffer - прямоугольник клеток, скопированный из слоя. Нулевое значение готово к использованию,
+	// а один буфер можно переиспользовать в CopyRect без новых выделений памяти
+	CellBuffer struct {
+		width  int
+		height int
+		cells  []Cell
+		// пустые клетки источника: Paste очищает их, а PasteTransparent пропускает
+		set []bool
+	}
+)
+
+func NewCellBuffer(width, height int) *CellBuffer {
+	b := CellBuffer{}
+	b.resize(width, height)
+
+	return &b
+}
+
+func (b *CellBuffer) Size() (int, int) {
+	return b.width, b.height
+}
+
+// Cell возвращает клетку буфера и false, если клетка пуста или вне буфера
+func (b *CellBuffer) Cell(x, y int) (Cell, bool) {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return Cell{}, false
+	}
+
+	index := y*b.width + x
+
+	return b.cells[index], b.set[index]
+}
+
+func (b *CellBuffer) SetCell(x, y int, cell Cell) {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return
+	}
+
+	index := y*b.width + x
+	b.cells[index] = cell
+	b.set[index] = true
+}
+
+// resize меняет размер буфера и очищает его, сохраняя уже выделенную память
+func (b *CellBuffer) resize(width, height int) {
+	width = max(width, 0)
+	height = max(height, 0)
+	count := width * height
+
+	if cap(b.cells) < count {
+		b.cells = make([]Cell, count)
+		b.set = make([]bool, count)
+	} else {
+		b.cells = b.cells[:count]
+		b.set = b.set[:count]
+		clear(b.cells)
+		clear(b.set)
+	}
+
+	b.width = width
+	b.height = height
+}
+
+// blit
+
+// CopyRect копирует клетки rect в buffer, меняя его размер на размер rect
+func (ctx *Context) CopyRect(rect Rect, buffer *CellBuffer) {
+	if rect.Empty() {
+		buffer.resize(0, 0)
+
+		return
+	}
+
+	buffer.resize(rect.Width, rect.Height)
+
+	for y := range rect.Height {
+		for x := range rect.Width {
+			cell, ok := ctx.lookupLocalCell(rect.X+x, rect.Y+y)
+			if !ok {
+				continue
+			}
+
+			buffer.SetCell(x, y, cell)
+		}
+	}
+}
+
+// Paste рисует buffer с левым верхним углом в (x, y). Пустые клетки буфера очищают клетки слоя
+func (ctx *Context) Paste(x, y int, buffer *CellBuffer) {
+	for bufferY := range buffer.height {
+		for bufferX := range buffer.width {
+			cell, ok := buffer.Cell(bufferX, bufferY)
+			if !ok {
+				ctx.clearLocalCell(x+bufferX, y+bufferY)
+				continue
+			}
+
+			ctx.setLocalCell(x+bufferX, y+bufferY, cell)
+		}
+	}
+}
+
+// PasteTransparent рисует buffer как Paste, но пропускает пустые клетки и клетки с символом transparent,
+// оставляя под ними то, что уже нарисовано
+func (ctx *Context) PasteTransparent(x, y int, buffer *CellBuffer, transparent rune) {
+	for bufferY := range buffer.height {
+		for bufferX := range buffer.width {
+			cell, ok := buffer.Cell(bufferX, bufferY)
+			if !ok || cell.Symbol == transparent {
+				continue
+			}
+
+			ctx.setLocalCell(x+bufferX, y+bufferY, cell)
+		}
+	}
+}
+
+// MoveRect переносит клетки rect так, чтобы его левый верхний угол оказался в (x, y).
+// Освободившиеся клетки очищаются, прямоугольники могут пересекаться
+func (ctx *Context) MoveRect(rect Rect, x, y int) {
+	if rect.Empty() {
+		return
+	}
+
+	buffer := CellBuffer{}
+	ctx.CopyRect(rect, &buffer)
+
+	for clearY := rect.Y; clearY < rect.Y+rect.Height; clearY++ {
+		for clearX := rect.X; clearX < rect.X+rect.Width; clearX++ {
+			ctx.clearLocalCell(clearX, clearY)
+		}
+	}
+
+	ctx.Paste(x, y, &buffer)
+}
+
+// fill
+
+// FloodFill заменяет на cell связную по сторонам область клеток с тем же символом и цветами, что у (x, y).
+// В Context из Sub заливка не выходит за его область, в остальных - за видимую часть экрана.
+// Возвращает количество залитых клеток
+func (ctx *Context) FloodFill(x, y int, cell Cell) int {
+	limit := ctx.fillLimit()
+	if !limit.Contains(x, y) {
+		return 0
+	}
+
+	target := ctx.getLocalCell(x, y)
+
+	// область Sub может быть сколь угодно большой, поэтому посещённые клетки хранятся в карте
+	visited := make(map[[2]int]struct{})
+	stack := []int{x, y}
+
+	filled := 0
+	for len(stack) > 0 {
+		currentX := stack[len(stack)-2]
+		currentY := stack[len(stack)-1]
+		stack = stack[:len(stack)-2]
+
+		if !limit.Contains(currentX, currentY) {
+			continue
+		}
+
+		key := [2]int{currentX, currentY}
+		_, ok := visited[key]
+		if ok {
+			continue
+		}
+		visited[key] = struct{}{}
+
+		currentCell := ctx.getLocalCell(currentX, currentY)
+		if !fillMatches(currentCell, target) {
+			continue
+		}
+
+		ctx.setLocalCell(currentX, currentY, cell)
+		filled++
+
+		stack = append(stack,
+			currentX+1, currentY,
+			currentX-1, currentY,
+			currentX, currentY+1,
+			currentX, currentY-1,
+		)
+	}
+
+	return filled
+}
+
+// fillLimit возвращает прямоугольник, за который не выходит FloodFill, в локальных координатах ctx
+func (ctx *Context) fillLimit() Rect {
+	localRect, ok := ctx.Region()
+	if ok {
+		return localRect
+	}
+
+	// пустой холст бесконечен, а нарисованная часть может быть разбросана далеко, поэтому заливка ограничена экраном
+	visibleRect := NewRect(0, 0, *ctx.viewSizeX, *ctx.viewSizeY)
+	if !ctx.layer.screenSpace {
+		mainViewport := ctx.viewports.main()
+		visibleRect = NewRect(*mainViewport.cameraX, *mainViewport.cameraY, mainViewport.rect.Width, mainViewport.rect.Height)
+	}
+
+	return visibleRect
+}
+
+func fillMatches(cell, target Cell) bool {
+	return cell.Symbol == target.Symbol && cell.Foreground == target.Foreground && cell.Background == target.Background
+}
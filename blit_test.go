@@ -0,0 +1,32 @@
+package gui_test
+
+import (
+	"testing"
+
+	"github.com/gggallahad/gui"
+	"github.com/gggallahad/gui/guitest"
+)
+
+func TestFloodFillLimits(t *testing.T) {
+	h := guitest.NewStarted(t, 4, 2)
+
+	filled, subFilled := 0, 0
+	h.Draw(t, func(ctx *gui.Context) {
+		// клетки далеко друг от друга раньше заставляли выделять память на весь охватывающий прямоугольник
+		ctx.SetCell(-1_000_000, -1_000_000, gui.Cell{Symbol: 'x'})
+		ctx.SetCell(1_000_000, 1_000_000, gui.Cell{Symbol: 'x'})
+
+		filled = ctx.FloodFill(0, 0, gui.Cell{Symbol: '.'})
+
+		sub := ctx.Sub(gui.NewRect(-1_000_000, -1_000_000, 3, 3))
+		subFilled = sub.FloodFill(1, 1, gui.Cell{Symbol: 'o'})
+	})
+
+	if filled != 8 {
+		t.Fatalf("filled %d cells, want 8", filled)
+	}
+	if subFilled != 8 {
+		t.Fatalf("filled %d cells in Sub, want 8", subFilled)
+	}
+	h.AssertString(t, "....\n....")
+}
@@ -0,0 +1,361 @@
+package gui
+
+import "maps"
+
+type (
+	BorderStyle int
+
+	// arms - стороны клетки, к которым подходят линии: верх, право, низ, лево
+	arms uint8
+
+	// boxGlyph - стороны символа рамки и те из них, что нарисованы двойной линией
+	boxGlyph struct {
+		arms   arms
+		double arms
+	}
+
+	// boxCell - настоящие стороны символа рамки в клетке слоя. По одному символу их не восстановить:
+	// конец линии рисуется тем же символом, что и её середина. Запись в клетку чем-то другим их забывает
+	boxCell struct {
+		arms   arms
+		double arms
+	}
+
+	// mixedBoxKey - пересечение, у которого горизонтальные стороны одного стиля, а вертикальные - другого
+	mixedBoxKey struct {
+		arms             arms
+		horizontalDouble bool
+	}
+
+	boxCellKey struct {
+		x int
+		y int
+	}
+)
+
+const (
+	BorderSingle BorderStyle = iota
+	BorderDouble
+	BorderRounded
+	BorderHeavy
+	BorderASCII
+)
+
+const (
+	armUp arms = 1 << iota
+	armRight
+	armDown
+	armLeft
+
+	armsHorizontal arms = armLeft | armRight
+	armsVertical   arms = armUp | armDown
+)
+
+var (
+	// символы по маске arms. Клетка с одной стороной рисуется целой линией, чтобы концы линий не обрывались
+	borderGlyphs map[BorderStyle][16]rune = map[BorderStyle][16]rune{
+		BorderSingle:  {' ', '│', '─', '└', '│', '│', '┌', '├', '─', '┘', '─', '┴', '┐', '┤', '┬', '┼'},
+		BorderRounded: {' ', '│', '─', '╰', '│', '│', '╭', '├', '─', '╯', '─', '┴', '╮', '┤', '┬', '┼'},
+		BorderHeavy:   {' ', '┃', '━', '┗', '┃', '┃', '┏', '┣', '━', '┛', '━', '┻', '┓', '┫', '┳', '╋'},
+		BorderDouble:  {' ', '║', '═', '╚', '║', '║', '╔', '╠', '═', '╝', '═', '╩', '╗', '╣', '╦', '╬'},
+		BorderASCII:   {' ', '|', '-', '+', '|', '|', '+', '+', '-', '+', '-', '+', '+', '+', '+', '+'},
+	}
+
+	// пересечения одинарных и двойных линий. Символы есть, только если стиль общий у каждой оси
+	mixedBoxGlyphs map[mixedBoxKey]rune = map[mixedBoxKey]rune{
+		{armRight | armDown, true}:             '╒',
+		{armLeft | armDown, true}:              '╕',
+		{armUp | armRight, true}:               '╘',
+		{armUp | armLeft, true}:                '╛',
+		{armsVertical | armRight, true}:        '╞',
+		{armsVertical | armLeft, true}:         '╡',
+		{armsHorizontal | armDown, true}:       '╤',
+		{armsHorizontal | armUp, true}:         '╧',
+		{armsHorizontal | armsVertical, true}:  '╪',
+		{armRight | armDown, false}:            '╓',
+		{armLeft | armDown, false}:             '╖',
+		{armUp | armRight, false}:              '╙',
+		{armUp | armLeft, false}:               '╜',
+		{armsVertical | armRight, false}:       '╟',
+		{armsVertical | armLeft, false}:        '╢',
+		{armsHorizontal | armDown, false}:      '╥',
+		{armsHorizontal | armUp, false}:        '╨',
+		{armsHorizontal | armsVertical, false}: '╫',
+	}
+
+	// обратная таблица для символов рамки, нарисованных не через Draw*, например SetText
+	boxGlyphs map[rune]boxGlyph = newBoxGlyphs()
+)
+
+func newBoxGlyphs() map[rune]boxGlyph {
+	glyphs := make(map[rune]boxGlyph)
+
+	// символы ASCII в таблицу не входят: '+', '-' и '|' в обычном тексте не должны становиться пересечениями
+	styles := []BorderStyle{BorderSingle, BorderRounded, BorderHeavy, BorderDouble}
+	for _, style := range styles {
+		table := borderGlyphs[style]
+		for mask := range table {
+			glyphArms := arms(mask)
+
+			// у линии из одной стороны тот же символ, что у целой линии
+			switch glyphArms {
+			case armUp, armDown:
+				glyphArms = armsVertical
+			case armLeft, armRight:
+				glyphArms = armsHorizontal
+			case 0:
+				continue
+			}
+
+			_, ok := glyphs[table[mask]]
+			if ok {
+				continue
+			}
+
+			var double arms
+			if style == BorderDouble {
+				double = glyphArms
+			}
+
+			glyphs[table[mask]] = boxGlyph{
+				arms:   glyphArms,
+				double: double,
+			}
+		}
+	}
+
+	for key, glyph := range mixedBoxGlyphs {
+		double := key.arms & armsVertical
+		if key.horizontalDouble {
+			double = key.arms & armsHorizontal
+		}
+
+		glyphs[glyph] = boxGlyph{
+			arms:   key.arms,
+			double: double,
+		}
+	}
+
+	return glyphs
+}
+
+// junctionGlyph выбирает символ style для сторон lineArms, из которых double нарисованы двойной линией.
+// Одинарные, скруглённые и двойные линии соединяются смешанными символами вроде ╟ и ╪. Если у одной оси
+// стороны разных стилей, ось рисуется стилем style. Жирные и ASCII линии не смешиваются: символ берётся из style
+func junctionGlyph(style BorderStyle, lineArms, double arms) rune {
+	table, ok := borderGlyphs[style]
+	if !ok {
+		table = borderGlyphs[BorderSingle]
+	}
+
+	if double == 0 || style == BorderHeavy || style == BorderASCII {
+		return table[lineArms]
+	}
+
+	horizontalDouble := axisDouble(lineArms&armsHorizontal, double, style)
+	verticalDouble := axisDouble(lineArms&armsVertical, double, style)
+
+	// у линии без второй оси стиль один
+	switch {
+	case lineArms&armsHorizontal == 0:
+		horizontalDouble = verticalDouble
+	case lineArms&armsVertical == 0:
+		verticalDouble = horizontalDouble
+	}
+
+	if horizontalDouble != verticalDouble {
+		key := mixedBoxKey{
+			arms:             lineArms,
+			horizontalDouble: horizontalDouble,
+		}
+
+		return mixedBoxGlyphs[key]
+	}
+
+	if horizontalDouble {
+		return borderGlyphs[BorderDouble][lineArms]
+	}
+
+	if style == BorderDouble {
+		return borderGlyphs[BorderSingle][lineArms]
+	}
+
+	return table[lineArms]
+}
+
+func axisDouble(axisArms, double arms, style BorderStyle) bool {
+	switch double & axisArms {
+	case 0:
+		return false
+	case axisArms:
+		return true
+	default:
+		return style == BorderDouble
+	}
+}
+
+func (l *layer) forgetBoxCell(x, y int) {
+	if len(l.boxCells) == 0 {
+		return
+	}
+
+	key := boxCellKey{
+		x: x,
+		y: y,
+	}
+	delete(l.boxCells, key)
+}
+
+// forgetBoxCells забывает стороны клеток, для которых erased возвращает true
+func (l *layer) forgetBoxCells(erased func(x, y int) bool) {
+	maps.DeleteFunc(l.boxCells, func(key boxCellKey, _ boxCell) bool {
+		return erased(key.x, key.y)
+	})
+}
+
+// draw
+
+// DrawHLine рисует горизонтальную линию длины length вправо от (x, y) символами рамки style.
+// Линия соединяется с уже нарисованными в этом слое линиями подходящими символами пересечений
+func (ctx *Context) DrawHLine(x, y, length int, style BorderStyle, foreground, background Color) {
+	for i := range length {
+		var lineArms arms
+		if i > 0 {
+			lineArms |= armLeft
+		}
+		if i < length-1 {
+			lineArms |= armRight
+		}
+		if length == 1 {
+			lineArms = armsHorizontal
+		}
+
+		ctx.mergeBoxCell(x+i, y, lineArms, style, foreground, background)
+	}
+}
+
+// DrawVLine рисует вертикальную линию длины length вниз от (x, y), см. DrawHLine
+func (ctx *Context) DrawVLine(x, y, length int, style BorderStyle, foreground, background Color) {
+	for i := range length {
+		var lineArms arms
+		if i > 0 {
+			lineArms |= armUp
+		}
+		if i < length-1 {
+			lineArms |= armDown
+		}
+		if length == 1 {
+			lineArms = armsVertical
+		}
+
+		ctx.mergeBoxCell(x, y+i, lineArms, style, foreground, background)
+	}
+}
+
+// DrawBox рисует рамку по краю rect символами style, соединяя её с уже нарисованными линиями
+func (ctx *Context) DrawBox(rect Rect, style BorderStyle, foreground, background Color) {
+	if rect.Empty() {
+		return
+	}
+
+	if rect.Height == 1 {
+		ctx.DrawHLine(rect.X, rect.Y, rect.Width, style, foreground, background)
+		return
+	}
+
+	if rect.Width == 1 {
+		ctx.DrawVLine(rect.X, rect.Y, rect.Height, style, foreground, background)
+		return
+	}
+
+	left := rect.X
+	top := rect.Y
+	right := rect.X + rect.Width - 1
+	bottom := rect.Y + rect.Height - 1
+
+	ctx.mergeBoxCell(left, top, armRight|armDown, style, foreground, background)
+	ctx.mergeBoxCell(right, top, armLeft|armDown, style, foreground, background)
+	ctx.mergeBoxCell(left, bottom, armRight|armUp, style, foreground, background)
+	ctx.mergeBoxCell(right, bottom, armLeft|armUp, style, foreground, background)
+
+	for x := left + 1; x < right; x++ {
+		ctx.mergeBoxCell(x, top, armsHorizontal, style, foreground, background)
+		ctx.mergeBoxCell(x, bottom, armsHorizontal, style, foreground, background)
+	}
+
+	for y := top + 1; y < bottom; y++ {
+		ctx.mergeBoxCell(left, y, armsVertical, style, foreground, background)
+		ctx.mergeBoxCell(right, y, armsVertical, style, foreground, background)
+	}
+}
+
+// DrawRect рисует клетками cell контур rect
+func (ctx *Context) DrawRect(rect Rect, cell Cell) {
+	if rect.Empty() {
+		return
+	}
+
+	for x := rect.X; x < rect.X+rect.Width; x++ {
+		ctx.setLocalCell(x, rect.Y, cell)
+		ctx.setLocalCell(x, rect.Y+rect.Height-1, cell)
+	}
+
+	for y := rect.Y; y < rect.Y+rect.Height; y++ {
+		ctx.setLocalCell(rect.X, y, cell)
+		ctx.setLocalCell(rect.X+rect.Width-1, y, cell)
+	}
+}
+
+// FillRect заполняет rect клетками cell
+func (ctx *Context) FillRect(rect Rect, cell Cell) {
+	for y := rect.Y; y < rect.Y+rect.Height; y++ {
+		for x := rect.X; x < rect.X+rect.Width; x++ {
+			ctx.setLocalCell(x, y, cell)
+		}
+	}
+}
+
+func (ctx *Context) mergeBoxCell(x, y int, lineArms arms, style BorderStyle, foreground, background Color) {
+	layerX, layerY, ok := ctx.region.toLayer(x, y)
+	if !ok {
+		return
+	}
+
+	key := boxCellKey{
+		x: layerX,
+		y: layerY,
+	}
+
+	var existingArms, existingDouble arms
+	existingBox, ok := ctx.layer.boxCells[key]
+	if ok {
+		existingArms = existingBox.arms
+		existingDouble = existingBox.double
+	} else {
+		existingGlyph, ok := boxGlyphs[ctx.getLocalCell(x, y).Symbol]
+		if ok {
+			existingArms = existingGlyph.arms
+			existingDouble = existingGlyph.double
+		}
+	}
+
+	// стороны новой линии получают её стиль, остальные сохраняют свой
+	double := existingDouble &^ lineArms
+	if style == BorderDouble {
+		double |= lineArms
+	}
+	lineArms |= existingArms
+
+	lineCell := Cell{
+		Symbol:     junctionGlyph(style, lineArms, double),
+		Foreground: foreground,
+		Background: background,
+	}
+
+	ctx.setLocalCell(x, y, lineCell)
+
+	ctx.layer.boxCells[key] = boxCell{
+		arms:   lineArms,
+		double: double,
+	}
+}
@@ -0,0 +1,110 @@
+package gui_test
+
+import (
+	"testing"
+
+	"github.com/gggallahad/gui"
+	"github.com/gggallahad/gui/guitest"
+)
+
+func TestBoxJunctionsIgnoreDrawingOrder(t *testing.T) {
+	d := gui.DefaultColor
+
+	tests := []struct {
+		name string
+		draw func(ctx *gui.Context)
+		want string
+	}{
+		{
+			name: "line then box",
+			draw: func(ctx *gui.Context) {
+				ctx.DrawVLine(2, 0, 3, gui.BorderSingle, d, d)
+				ctx.DrawHLine(0, 1, 5, gui.BorderSingle, d, d)
+				ctx.DrawBox(gui.NewRect(0, 0, 5, 3), gui.BorderSingle, d, d)
+			},
+			want: "┌─┬─┐\n├─┼─┤\n└─┴─┘",
+		},
+		{
+			name: "box then line",
+			draw: func(ctx *gui.Context) {
+				ctx.DrawBox(gui.NewRect(0, 0, 5, 3), gui.BorderSingle, d, d)
+				ctx.DrawVLine(2, 0, 3, gui.BorderSingle, d, d)
+				ctx.DrawHLine(0, 1, 5, gui.BorderSingle, d, d)
+			},
+			want: "┌─┬─┐\n├─┼─┤\n└─┴─┘",
+		},
+		{
+			name: "double line then box",
+			draw: func(ctx *gui.Context) {
+				ctx.DrawVLine(2, 0, 3, gui.BorderDouble, d, d)
+				ctx.DrawBox(gui.NewRect(0, 0, 5, 3), gui.BorderDouble, d, d)
+			},
+			want: "╔═╦═╗\n║ ║ ║\n╚═╩═╝",
+		},
+		{
+			name: "redrawn cell forgets its arms",
+			draw: func(ctx *gui.Context) {
+				ctx.DrawHLine(0, 1, 5, gui.BorderSingle, d, d)
+				ctx.ClearRow(1)
+				ctx.DrawBox(gui.NewRect(0, 0, 5, 3), gui.BorderSingle, d, d)
+			},
+			want: "┌───┐\n│   │\n└───┘",
+		},
+		{
+			name: "ascii text is not a junction",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(0, 1, "a+b-|", d, d)
+				ctx.DrawVLine(1, 0, 3, gui.BorderSingle, d, d)
+				ctx.DrawVLine(3, 0, 3, gui.BorderSingle, d, d)
+				ctx.DrawVLine(4, 0, 3, gui.BorderSingle, d, d)
+			},
+			want: " │ ││\na│b││\n │ ││",
+		},
+		{
+			name: "single line across double box",
+			draw: func(ctx *gui.Context) {
+				ctx.DrawBox(gui.NewRect(0, 0, 5, 3), gui.BorderDouble, d, d)
+				ctx.DrawHLine(0, 1, 5, gui.BorderSingle, d, d)
+			},
+			want: "╔═══╗\n╟───╢\n╚═══╝",
+		},
+		{
+			name: "double line across single box",
+			draw: func(ctx *gui.Context) {
+				ctx.DrawBox(gui.NewRect(0, 0, 5, 3), gui.BorderSingle, d, d)
+				ctx.DrawVLine(2, 0, 3, gui.BorderDouble, d, d)
+			},
+			want: "┌─╥─┐\n│ ║ │\n└─╨─┘",
+		},
+		{
+			name: "mixed cross in any order",
+			draw: func(ctx *gui.Context) {
+				ctx.DrawVLine(1, 0, 3, gui.BorderSingle, d, d)
+				ctx.DrawHLine(0, 1, 5, gui.BorderDouble, d, d)
+				ctx.DrawHLine(0, 2, 5, gui.BorderDouble, d, d)
+				ctx.DrawVLine(3, 0, 3, gui.BorderSingle, d, d)
+			},
+			want: " │ │ \n═╪═╪═\n═╧═╧═",
+		},
+		{
+			name: "mixed glyph drawn as text",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(2, 1, "╟", d, d)
+				ctx.DrawHLine(3, 1, 2, gui.BorderSingle, d, d)
+				ctx.DrawHLine(0, 1, 2, gui.BorderSingle, d, d)
+				ctx.DrawHLine(2, 1, 1, gui.BorderSingle, d, d)
+			},
+			want: "     \n──╫──\n     ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := guitest.NewStarted(t, 5, 3)
+
+			h.Draw(t, test.draw)
+
+			h.AssertString(t, test.want)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package gui
+
+const (
+	chunkSize int = 64
+)
+
+type (
+	chunkKey struct {
+		X int
+		Y int
+	}
+
+	chunk struct {
+		cells [chunkSize * chunkSize]Cell
+		set   [chunkSize * chunkSize]bool
+		count int
+	}
+
+	cellPosition struct {
+		X int
+		Y int
+	}
+
+	// canvas хранит клетки кусками chunkSize x chunkSize, создавая кусок при первой записи в него.
+	// Координаты любые, в том числе отрицательные. Незаписанная клетка считается пустой
+	canvas struct {
+		chunks map[chunkKey]*chunk
+	}
+)
+
+func newCanvas() *canvas {
+	chunks := make(map[chunkKey]*chunk)
+
+	c := canvas{
+		chunks: chunks,
+	}
+
+	return &c
+}
+
+func (c *canvas) set(x, y int, cell Cell) {
+	key, index := chunkPosition(x, y)
+
+	currentChunk, ok := c.chunks[key]
+	if !ok {
+		currentChunk = &chunk{}
+		c.chunks[key] = currentChunk
+	}
+
+	if !currentChunk.set[index] {
+		currentChunk.set[index] = true
+		currentChunk.count++
+	}
+
+	currentChunk.cells[index] = cell
+}
+
+func (c *canvas) get(x, y int) (Cell, bool) {
+	key, index := chunkPosition(x, y)
+
+	currentChunk, ok := c.chunks[key]
+	if !ok || !currentChunk.set[index] {
+		return Cell{}, false
+	}
+
+	cell := currentChunk.cells[index]
+
+	return cell, true
+}
+
+// erase делает клетку пустой. Кусок без записанных клеток освобождается
+func (c *canvas) erase(x, y int) {
+	key, index := chunkPosition(x, y)
+
+	currentChunk, ok := c.chunks[key]
+	if !ok || !currentChunk.set[index] {
+		return
+	}
+
+	currentChunk.set[index] = false
+	currentChunk.cells[index] = Cell{}
+	currentChunk.count--
+
+	if currentChunk.count == 0 {
+		delete(c.chunks, key)
+	}
+}
+
+func (c *canvas) clear() {
+	c.chunks = make(map[chunkKey]*chunk)
+}
+
+func (c *canvas) eraseRow(y int) {
+	chunkY := floorDiv(y, chunkSize)
+	for key := range c.chunks {
+		if key.Y != chunkY {
+			continue
+		}
+
+		for localX := range chunkSize {
+			c.erase(key.X*chunkSize+localX, y)
+		}
+	}
+}
+
+func (c *canvas) eraseColumn(x int) {
+	chunkX := floorDiv(x, chunkSize)
+	for key := range c.chunks {
+		if key.X != chunkX {
+			continue
+		}
+
+		for localY := range chunkSize {
+			c.erase(x, key.Y*chunkSize+localY)
+		}
+	}
+}
+
+// bounds возвращает наименьший прямоугольник, содержащий все записанные клетки
+func (c *canvas) bounds() (Rect, bool) {
+	var bounds Rect
+	for key, currentChunk := range c.chunks {
+		for index := range currentChunk.set {
+			if !currentChunk.set[index] {
+				continue
+			}
+
+			x := key.X*chunkSize + index%chunkSize
+			y := key.Y*chunkSize + index/chunkSize
+			bounds = bounds.Union(NewRect(x, y, 1, 1))
+		}
+	}
+
+	return bounds, !bounds.Empty()
+}
+
+// cellsIn возвращает записанные клетки внутри rect в произвольном порядке.
+// Обходятся только существующие куски, поэтому размер rect не важен
+func (c *canvas) cellsIn(rect Rect) []cellPosition {
+	positions := []cellPosition{}
+	for key, currentChunk := range c.chunks {
+		chunkRect := NewRect(key.X*chunkSize, key.Y*chunkSize, chunkSize, chunkSize)
+		overlap := chunkRect.Intersect(rect)
+
+		for y := overlap.Y; y < overlap.Y+overlap.Height; y++ {
+			for x := overlap.X; x < overlap.X+overlap.Width; x++ {
+				index := (y-chunkRect.Y)*chunkSize + (x - chunkRect.X)
+				if currentChunk.set[index] {
+					positions = append(positions, cellPosition{X: x, Y: y})
+				}
+			}
+		}
+	}
+
+	return positions
+}
+
+// util
+
+func chunkPosition(x, y int) (chunkKey, int) {
+	key := chunkKey{
+		X: floorDiv(x, chunkSize),
+		Y: floorDiv(y, chunkSize),
+	}
+
+	localX := x - key.X*chunkSize
+	localY := y - key.Y*chunkSize
+	index := localY*chunkSize + localX
+
+	return key, index
+}
+
+// деление с округлением вниз, чтобы -1 попадала в кусок -1, а не 0
+func floorDiv(value, divisor int) int {
+	quotient := value / divisor
+	if value%divisor != 0 && (value < 0) != (divisor < 0) {
+		quotient--
+	}
+
+	return quotient
+}
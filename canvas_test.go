@@ -0,0 +1,128 @@
+package gui
+
+import (
+	"testing"
+)
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct {
+		value int
+		want  int
+	}{
+		{value: 0, want: 0},
+		{value: 1, want: 0},
+		{value: 63, want: 0},
+		{value: 64, want: 1},
+		{value: 127, want: 1},
+		{value: 128, want: 2},
+		{value: -1, want: -1},
+		{value: -63, want: -1},
+		{value: -64, want: -1},
+		{value: -65, want: -2},
+		{value: -128, want: -2},
+		{value: -129, want: -3},
+	}
+
+	for _, test := range tests {
+		got := floorDiv(test.value, chunkSize)
+		if got != test.want {
+			t.Errorf("floorDiv(%d, %d) = %d, want %d", test.value, chunkSize, got, test.want)
+		}
+	}
+}
+
+func TestChunkPosition(t *testing.T) {
+	tests := []struct {
+		x         int
+		y         int
+		wantKey   chunkKey
+		wantIndex int
+	}{
+		{x: 0, y: 0, wantKey: chunkKey{X: 0, Y: 0}, wantIndex: 0},
+		{x: 63, y: 0, wantKey: chunkKey{X: 0, Y: 0}, wantIndex: 63},
+		{x: 64, y: 0, wantKey: chunkKey{X: 1, Y: 0}, wantIndex: 0},
+		{x: 0, y: 63, wantKey: chunkKey{X: 0, Y: 0}, wantIndex: 63 * chunkSize},
+		{x: 0, y: 64, wantKey: chunkKey{X: 0, Y: 1}, wantIndex: 0},
+		{x: -1, y: 0, wantKey: chunkKey{X: -1, Y: 0}, wantIndex: 63},
+		{x: -64, y: 0, wantKey: chunkKey{X: -1, Y: 0}, wantIndex: 0},
+		{x: -65, y: 0, wantKey: chunkKey{X: -2, Y: 0}, wantIndex: 63},
+		{x: 0, y: -1, wantKey: chunkKey{X: 0, Y: -1}, wantIndex: 63 * chunkSize},
+		{x: -1, y: -65, wantKey: chunkKey{X: -1, Y: -2}, wantIndex: 63*chunkSize + 63},
+	}
+
+	for _, test := range tests {
+		key, index := chunkPosition(test.x, test.y)
+		if key != test.wantKey || index != test.wantIndex {
+			t.Errorf("chunkPosition(%d, %d) = %v, %d, want %v, %d", test.x, test.y, key, index, test.wantKey, test.wantIndex)
+		}
+	}
+}
+
+func TestCanvasAcrossChunks(t *testing.T) {
+	c := newCanvas()
+
+	positions := []cellPosition{
+		{X: -65, Y: 0},
+		{X: -64, Y: 0},
+		{X: -1, Y: -1},
+		{X: 0, Y: 0},
+		{X: 63, Y: 63},
+		{X: 64, Y: 64},
+	}
+
+	for i, position := range positions {
+		c.set(position.X, position.Y, Cell{Symbol: rune('a' + i)})
+	}
+
+	for i, position := range positions {
+		cell, ok := c.get(position.X, position.Y)
+		if !ok || cell.Symbol != rune('a'+i) {
+			t.Errorf("get(%d, %d) = %q, %v, want %q", position.X, position.Y, cell.Symbol, ok, rune('a'+i))
+		}
+	}
+
+	// соседние клетки по другую сторону границы куска не записаны
+	for _, position := range []cellPosition{{X: -66, Y: 0}, {X: -63, Y: 0}, {X: -1, Y: 0}, {X: 0, Y: -1}, {X: 64, Y: 63}} {
+		_, ok := c.get(position.X, position.Y)
+		if ok {
+			t.Errorf("get(%d, %d) reports a cell that was never set", position.X, position.Y)
+		}
+	}
+
+	if len(c.chunks) != 5 {
+		t.Fatalf("%d chunks, want 5", len(c.chunks))
+	}
+
+	bounds, ok := c.bounds()
+	want := NewRect(-65, -1, 130, 66)
+	if !ok || bounds != want {
+		t.Fatalf("bounds() = %v, %v, want %v", bounds, ok, want)
+	}
+
+	// куски (-2, 0) и (-1, 0) держат по одной клетке и освобождаются. Повторное стирание ничего не ломает
+	c.erase(-65, 0)
+	c.erase(-64, 0)
+	c.erase(-65, 0)
+
+	_, ok = c.chunks[chunkKey{X: -2, Y: 0}]
+	if ok {
+		t.Fatal("empty chunk (-2, 0) was not released")
+	}
+
+	_, ok = c.chunks[chunkKey{X: -1, Y: 0}]
+	if ok {
+		t.Fatal("empty chunk (-1, 0) was not released")
+	}
+
+	c.eraseRow(-1)
+	c.eraseColumn(64)
+
+	if len(c.chunks) != 1 {
+		t.Fatalf("%d chunks after erasing, want 1", len(c.chunks))
+	}
+
+	got := c.cellsIn(NewRect(-100, -100, 200, 200))
+	if len(got) != 2 {
+		t.Fatalf("cellsIn() = %v, want (0, 0) and (63, 63)", got)
+	}
+}
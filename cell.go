@@ -2,10 +2,28 @@ package gui
 
 type (
 	Cell struct {
-		Symbol     rune
+		Symbol rune
+		// остальные руны графемного кластера: комбинирующие знаки, ZWJ-последовательности
+		Combining  string
 		Foreground Color
 		Background Color
+		Attributes Attribute
 	}
+
+	// Attribute - набор флагов начертания, флаги объединяются через '|'
+	Attribute uint16
+)
+
+const (
+	AttrBold Attribute = 1 << iota
+	AttrItalic
+	AttrUnderline
+	AttrReverse
+	AttrBlink
+	AttrStrikethrough
+	AttrDim
+
+	NoAttributes Attribute = 0
 )
 
 var (
@@ -15,5 +33,19 @@ var (
 		Symbol:     DefaultSymbol,
 		Foreground: DefaultColor,
 		Background: DefaultColor,
+		Attributes: NoAttributes,
 	}
 )
+
+func (a Attribute) Has(attribute Attribute) bool {
+	return a&attribute == attribute
+}
+
+func joinAttributes(attributes []Attribute) Attribute {
+	var joined Attribute
+	for i := range attributes {
+		joined |= attributes[i]
+	}
+
+	return joined
+}
@@ -1,6 +1,9 @@
 package gui
 
-import "github.com/nsf/termbox-go"
+import (
+	"fmt"
+	"strconv"
+)
 
 type (
 	Color struct {
@@ -18,29 +21,35 @@ var (
 	}
 )
 
-func (c *Color) toAttribute() termbox.Attribute {
-	if *c == DefaultColor {
-		return termbox.ColorDefault
+// ParseColor разбирает "#rrggbb", "#rgb" или "default"
+func ParseColor(text string) (Color, error) {
+	if text == "default" {
+		return DefaultColor, nil
 	}
 
-	attribute := termbox.RGBToAttribute(uint8(c.R), uint8(c.G), uint8(c.B))
+	if len(text) == 0 || text[0] != '#' {
+		return DefaultColor, fmt.Errorf("%w: %q", ErrInvalidColor, text)
+	}
 
-	return attribute
-}
+	hex := text[1:]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
 
-// func (c *Color) fromAttribute(attribute termbox.Attribute) Color {
-// 	if attribute == termbox.ColorDefault {
-// 		color := DefaultColor
+	if len(hex) != 6 {
+		return DefaultColor, fmt.Errorf("%w: %q", ErrInvalidColor, text)
+	}
 
-// 		return color
-// 	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return DefaultColor, fmt.Errorf("%w: %q", ErrInvalidColor, text)
+	}
 
-// 	r, g, b := termbox.AttributeToRGB(attribute)
-// 	color := Color{
-// 		R: int(r),
-// 		G: int(g),
-// 		B: int(b),
-// 	}
+	color := Color{
+		R: int(value >> 16 & 0xFF),
+		G: int(value >> 8 & 0xFF),
+		B: int(value & 0xFF),
+	}
 
-// 	return color
-// }
+	return color, nil
+}
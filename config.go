@@ -2,6 +2,25 @@ package gui
 
 type (
 	ScreenConfig struct {
-		DefaultCell Cell
+		DefaultCell  Cell
+		DispatchMode DispatchMode
+		// если не задан, используется TermboxBackend
+		Backend Backend
+		// по умолчанию OutputAuto. Цвета Cell приводятся к палитре режима при выводе
+		OutputMode OutputMode
+		// по умолчанию GraphicsAuto. Картинки PlaceImage выводятся, только если Backend реализует RawWriter
+		Graphics GraphicsProtocol
+		// размер клетки в точках экрана, по умолчанию 10×20. По нему картинка Sixel вписывается в клетки
+		CellPixelWidth  int
+		CellPixelHeight int
 	}
+
+	DispatchMode int
+)
+
+const (
+	// события обрабатываются по одному, в порядке поступления, в горутине Run
+	DispatchSerial DispatchMode = iota
+	// каждое событие обрабатывается в отдельной горутине, порядок не гарантируется
+	DispatchConcurrent
 )
@@ -3,14 +3,19 @@ package gui
 import (
 	"context"
 	"math"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/nsf/termbox-go"
 )
 
 type (
 	Context struct {
-		cells       *[][]Cell
+		backend  Backend
+		renderer *renderer
+
+		layers      *layers
+		layer       *layer
+		region      region
 		defaultCell *Cell
 
 		viewPositionX *int
@@ -19,19 +24,40 @@ type (
 		viewSizeX *int
 		viewSizeY *int
 
+		viewports *viewports
+
+		graphics *graphics
+
 		stateIndex *int
 		states     *[]State
+		stateMutex *sync.RWMutex
+
+		stateHandlers *stateHandlers
 
 		killChannel chan struct{}
+		killOnce    *sync.Once
+		stopChannel chan struct{}
+
+		messages *queue[message]
+		// номер горутины Run: Do из неё выполняет task сразу, из остальных - через очередь
+		runGoroutine *atomic.Uint64
+
+		// таймеры привязаны к контексту Screen, а не к контексту обработчика, который отменяется после его завершения
+		timers      *timers
+		rootContext context.Context
 
-		handlerIndex int
+		// указатель, чтобы Abort работал и из контекстов, полученных через Layer
+		handlerIndex *int
 		context      context.Context
 		cancelFunc   context.CancelFunc
 	}
 )
 
-func newContext(defaultCell Cell) (*Context, error) {
-	cells := make([][]Cell, 0)
+func newContext(backend Backend, defaultCell Cell) (*Context, error) {
+	renderer := newRenderer()
+
+	layers := newLayers()
+	layer, _ := layers.find(BaseLayer)
 
 	viewPositionX := 0
 	viewPositionY := 0
@@ -39,25 +65,54 @@ func newContext(defaultCell Cell) (*Context, error) {
 	viewSizeX := 0
 	viewSizeY := 0
 
+	viewports := newViewports(&viewPositionX, &viewPositionY)
+
+	graphics := newGraphics()
+
 	stateIndex := 0
 	states := []State{NoState}
+	stateMutex := sync.RWMutex{}
 
+	stateHandlers := newStateHandlers()
+
+	// Kill закрывает канал, а не пишет в него, чтобы не блокироваться внутри обработчика, который выполняется в горутине Run
 	killChannel := make(chan struct{})
+	killOnce := sync.Once{}
+	stopChannel := make(chan struct{})
+
+	messages := newQueue[message]()
+	runGoroutine := atomic.Uint64{}
+
+	timers := newTimers()
 
 	handlerIndex := 0
 	context, cancelFunc := context.WithCancel(context.Background())
 
 	ctx := Context{
-		cells:         &cells,
+		backend:       backend,
+		renderer:      renderer,
+		layers:        layers,
+		layer:         layer,
+		region:        region{},
 		defaultCell:   &defaultCell,
 		viewPositionX: &viewPositionX,
 		viewPositionY: &viewPositionY,
 		viewSizeX:     &viewSizeX,
 		viewSizeY:     &viewSizeY,
+		viewports:     viewports,
+		graphics:      graphics,
 		stateIndex:    &stateIndex,
 		states:        &states,
+		stateMutex:    &stateMutex,
+		stateHandlers: stateHandlers,
 		killChannel:   killChannel,
-		handlerIndex:  handlerIndex,
+		killOnce:      &killOnce,
+		stopChannel:   stopChannel,
+		messages:      messages,
+		runGoroutine:  &runGoroutine,
+		timers:        timers,
+		rootContext:   context,
+		handlerIndex:  &handlerIndex,
 		context:       context,
 		cancelFunc:    cancelFunc,
 	}
@@ -70,16 +125,30 @@ func (ctx *Context) newChildContext() *Context {
 	context, cancelFunc := context.WithCancel(ctx)
 
 	childContext := Context{
-		cells:         ctx.cells,
+		backend:       ctx.backend,
+		renderer:      ctx.renderer,
+		layers:        ctx.layers,
+		layer:         ctx.layer,
+		region:        ctx.region,
 		defaultCell:   ctx.defaultCell,
 		viewPositionX: ctx.viewPositionX,
 		viewPositionY: ctx.viewPositionY,
 		viewSizeX:     ctx.viewSizeX,
 		viewSizeY:     ctx.viewSizeY,
+		viewports:     ctx.viewports,
+		graphics:      ctx.graphics,
 		stateIndex:    ctx.stateIndex,
 		states:        ctx.states,
+		stateMutex:    ctx.stateMutex,
+		stateHandlers: ctx.stateHandlers,
 		killChannel:   ctx.killChannel,
-		handlerIndex:  handlerIndex,
+		killOnce:      ctx.killOnce,
+		stopChannel:   ctx.stopChannel,
+		messages:      ctx.messages,
+		runGoroutine:  ctx.runGoroutine,
+		timers:        ctx.timers,
+		rootContext:   ctx.rootContext,
+		handlerIndex:  &handlerIndex,
 		context:       context,
 		cancelFunc:    cancelFunc,
 	}
@@ -88,239 +157,192 @@ func (ctx *Context) newChildContext() *Context {
 }
 
 // draw
+//
+// все методы рисования меняют только хранилище клеток. Кадр собирается из него и выводится в Flush
 
 func (ctx *Context) SetViewPosition(viewPositionX, viewPositionY int) {
 	*ctx.viewPositionX = viewPositionX
 	*ctx.viewPositionY = viewPositionY
 }
 
+// UpdateViewContent собирает кадр видимой области без вывода на экран.
+// Flush делает это сам, поэтому вызывать UpdateViewContent перед ним не обязательно
 func (ctx *Context) UpdateViewContent() error {
-	foregroundAttribute := ctx.defaultCell.Foreground.toAttribute()
-	backgroundAttribute := ctx.defaultCell.Background.toAttribute()
-
-	// очистка видимой области
-	err := ctx.clearTermboxScreen(foregroundAttribute, backgroundAttribute)
-	if err != nil {
-		return err
-	}
-
-	// перерисовка клеток которые попадают в видимую область
-	viewEndY := min(*ctx.viewPositionY+*ctx.viewSizeY, len(*ctx.cells))
-	for y := *ctx.viewPositionY; y < viewEndY; y++ {
-		viewEndX := min(*ctx.viewPositionX+*ctx.viewSizeX, len((*ctx.cells)[y]))
-		for x := *ctx.viewPositionX; x < viewEndX; x++ {
-			ctx.setTermboxCell(x, y, (*ctx.cells)[y][x])
-		}
-	}
+	ctx.composeFrame()
 
 	return nil
 }
 
 func (ctx *Context) SetCell(x, y int, cell Cell) {
-	ctx.setlocalCell(x, y, cell)
-
-	ctx.setTermboxCell(x, y, cell)
+	ctx.setLocalCell(x, y, cell)
 }
 
-func (ctx *Context) setlocalCell(x, y int, cell Cell) {
-	// добавление недостающих строк
-	maxY := len(*ctx.cells) - 1
-	if y > maxY {
-		*ctx.cells = growSlice(*ctx.cells, y+1)
-		newRows := y - maxY
-		for range newRows {
-			*ctx.cells = append(*ctx.cells, []Cell{})
-		}
-	}
-
-	// добавление недостающего столбца в нужную строку
-	maxX := len((*ctx.cells)[y]) - 1
-	if x > maxX {
-		(*ctx.cells)[y] = growSlice((*ctx.cells)[y], x+1)
-		newColumns := x - maxX
-		for range newColumns {
-			(*ctx.cells)[y] = append((*ctx.cells)[y], *ctx.defaultCell)
-		}
+func (ctx *Context) setLocalCell(x, y int, cell Cell) {
+	x, y, ok := ctx.region.toLayer(x, y)
+	if !ok {
+		return
 	}
 
-	(*ctx.cells)[y][x] = cell
+	ctx.detachWideGlyph(x, y, cell.Symbol == ContinuationSymbol)
+	ctx.layer.canvas.set(x, y, cell)
+	ctx.layer.forgetBoxCell(x, y)
 }
 
-func (ctx *Context) setTermboxCell(x, y int, cell Cell) {
-	// отрисовка клетки со смещением по видимой области
-
-	x -= *ctx.viewPositionX
-	y -= *ctx.viewPositionY
+// detachWideGlyph заменяет пробелом половину широкого символа, которая осталась бы без пары после записи
+// в клетку слоя (x, y). continuation - в клетку пишется правая половина нового широкого символа
+func (ctx *Context) detachWideGlyph(x, y int, continuation bool) {
+	canvas := ctx.layer.canvas
 
-	foregroundAttribute := cell.Foreground.toAttribute()
-	backgroundAttribute := cell.Background.toAttribute()
-
-	termbox.SetCell(x, y, cell.Symbol, foregroundAttribute, backgroundAttribute)
-}
-
-func (ctx *Context) SetText(x, y int, text string, foreground, background Color) {
-	ctx.setLocalText(x, y, text, foreground, background)
-
-	ctx.setTermboxText(x, y, text, foreground, background)
-}
-
-func (ctx *Context) setLocalText(x, y int, text string, foreground, background Color) {
-	// добавление недостающих строк
-	maxY := len(*ctx.cells) - 1
-	if y > maxY {
-		*ctx.cells = growSlice(*ctx.cells, y+1)
-		newRows := y - maxY
-		for range newRows {
-			*ctx.cells = append(*ctx.cells, []Cell{})
-		}
+	oldCell, ok := canvas.get(x, y)
+	if !ok {
+		return
 	}
 
-	// добавление недостающих столбцов в нужную строку
-	maxX := len((*ctx.cells)[y]) - 1
-	newMaxX := x + len(text)
-	if newMaxX > maxX {
-		(*ctx.cells)[y] = growSlice((*ctx.cells)[y], newMaxX+1)
-		newColumns := newMaxX - maxX
-		for range newColumns {
-			(*ctx.cells)[y] = append((*ctx.cells)[y], *ctx.defaultCell)
+	if oldCell.Symbol == ContinuationSymbol {
+		if !continuation {
+			blankCell(canvas, x-1, y)
 		}
+
+		return
 	}
 
-	textCell := Cell{
-		Foreground: foreground,
-		Background: background,
+	nextCell, ok := canvas.get(x+1, y)
+	if ok && nextCell.Symbol == ContinuationSymbol {
+		blankCell(canvas, x+1, y)
 	}
+}
 
-	for _, symbox := range text {
-		textCell.Symbol = symbox
-		(*ctx.cells)[y][x] = textCell
-		x++
+func blankCell(canvas *canvas, x, y int) {
+	cell, ok := canvas.get(x, y)
+	if !ok {
+		return
 	}
+
+	cell.Symbol = DefaultSymbol
+	cell.Combining = ""
+
+	canvas.set(x, y, cell)
 }
 
-func (ctx *Context) setTermboxText(x, y int, text string, foreground, background Color) {
+// SetText рисует text одной строкой, attributes объединяются в одно начертание.
+// Широкие символы занимают две клетки, графемные кластеры не разрываются. Возвращает ширину text в клетках
+func (ctx *Context) SetText(x, y int, text string, foreground, background Color, attributes ...Attribute) int {
 	textCell := Cell{
 		Foreground: foreground,
 		Background: background,
+		Attributes: joinAttributes(attributes),
 	}
 
-	for _, symbol := range text {
-		textCell.Symbol = symbol
-		ctx.setTermboxCell(x, y, textCell)
-		x++
+	textCells := textToCells(text, textCell)
+
+	ctx.setLocalText(x, y, textCells)
+
+	return len(textCells)
+}
+
+func (ctx *Context) setLocalText(x, y int, textCells []Cell) {
+	for i := range textCells {
+		ctx.setLocalCell(x+i, y, textCells[i])
 	}
 }
 
+// SetRow заменяет строку y: клетки cells начинаются со столбца 0, остальная часть строки очищается
 func (ctx *Context) SetRow(y int, cells []Cell) {
 	ctx.ClearRow(y)
 
 	ctx.setLocalRow(y, cells)
-
-	ctx.setTermboxRow(y, cells)
 }
 
 func (ctx *Context) setLocalRow(y int, cells []Cell) {
-	// добавление недостающих строк
-	maxY := len(*ctx.cells) - 1
-	if y > maxY {
-		*ctx.cells = growSlice(*ctx.cells, y+1)
-		newRows := y - maxY
-		for range newRows {
-			*ctx.cells = append(*ctx.cells, []Cell{})
-		}
-	}
-
-	(*ctx.cells)[y] = cells
-}
-
-func (ctx *Context) setTermboxRow(y int, cells []Cell) {
-	// отрисовка всех клеток новой строки
-	for x := *ctx.viewPositionX; x < len(cells); x++ {
-		ctx.setTermboxCell(x, y, cells[x])
+	for x := range cells {
+		ctx.setLocalCell(x, y, cells[x])
 	}
 }
 
+// SetColumn заменяет столбец x: клетки cells начинаются со строки 0, остальная часть столбца очищается
 func (ctx *Context) SetColumn(x int, cells []Cell) {
 	ctx.ClearColumn(x)
 
 	ctx.setLocalColumn(x, cells)
-
-	ctx.setTermboxColumn(x, cells)
 }
 
 func (ctx *Context) setLocalColumn(x int, cells []Cell) {
-	// добавление недостающих строк
-	maxY := len(*ctx.cells) - 1
-	newMaxY := len(cells) - 1
-	if newMaxY > maxY {
-		*ctx.cells = growSlice(*ctx.cells, newMaxY+1)
-		newRows := newMaxY - maxY
-		for range newRows {
-			*ctx.cells = append(*ctx.cells, make([]Cell, 0, len(cells)))
-		}
+	for y := range cells {
+		ctx.setLocalCell(x, y, cells[y])
 	}
+}
 
-	// добавление недостающих столбцов в нужные строки
-	for y := range len(cells) {
-		maxX := len((*ctx.cells)[y]) - 1
-		if x > maxX {
-			(*ctx.cells)[y] = growSlice((*ctx.cells)[y], x+1)
-			newColumns := x - maxX
-			for range newColumns {
-				(*ctx.cells)[y] = append((*ctx.cells)[y], *ctx.defaultCell)
-			}
-		}
-	}
+func (ctx *Context) GetCell(x, y int) Cell {
+	localCell := ctx.getLocalCell(x, y)
 
-	// устанавливаем значения в столбец. Можно это было делать и в предыдущем цикле, но я не хочу засорять логику. Исправлю когда буду релизить
-	for y := range len(cells) {
-		(*ctx.cells)[y][x] = cells[y]
-	}
+	return localCell
 }
 
-func (ctx *Context) setTermboxColumn(x int, cells []Cell) {
-	// отрисовка всех клеток нового столбца
-	for y := *ctx.viewPositionY; y < len(cells); y++ {
-		ctx.setTermboxCell(x, y, cells[y])
+func (ctx *Context) getLocalCell(x, y int) Cell {
+	cell, ok := ctx.lookupLocalCell(x, y)
+	if !ok {
+		return ctx.layer.emptyCell(*ctx.defaultCell)
 	}
+
+	return cell
 }
 
-func (ctx *Context) GetCell(x, y int) Cell {
-	if y >= len(*ctx.cells) || x >= len((*ctx.cells)[y]) {
-		return *ctx.defaultCell
+// lookupLocalCell возвращает нарисованную клетку. false, если клетка пуста или вне области Sub
+func (ctx *Context) lookupLocalCell(x, y int) (Cell, bool) {
+	x, y, ok := ctx.region.toLayer(x, y)
+	if !ok {
+		return Cell{}, false
 	}
 
-	localCell := ctx.getLocalCell(x, y)
+	cell, ok := ctx.layer.canvas.get(x, y)
 
-	return localCell
+	return cell, ok
 }
 
-func (ctx *Context) getLocalCell(x, y int) Cell {
-	cell := (*ctx.cells)[y][x]
+// Bounds возвращает наименьший прямоугольник, содержащий все нарисованные клетки слоя. false, если слой пуст
+func (ctx *Context) Bounds() (Rect, bool) {
+	if !ctx.region.clipped {
+		bounds, ok := ctx.layer.canvas.bounds()
 
-	return cell
-}
+		return bounds, ok
+	}
 
-// func (ctx *Context) getTermboxCell(x, y int) Cell {
-// 	x -= *ctx.viewPositionX
-// 	y -= *ctx.viewPositionY
+	// в области Sub ищутся только её клетки, в локальных координатах
+	var bounds Rect
+	for _, position := range ctx.layer.canvas.cellsIn(ctx.region.clip) {
+		bounds = bounds.Union(NewRect(position.X-ctx.region.originX, position.Y-ctx.region.originY, 1, 1))
+	}
 
-// 	termboxCell := termbox.GetCell(x, y)
+	return bounds, !bounds.Empty()
+}
+
+func (ctx *Context) SetCursor(x, y int) {
+	x += ctx.region.originX
+	y += ctx.region.originY
 
-// 	foregroundColor := (&Color{}).fromAttribute(termboxCell.Fg)
-// 	backgroundColor := (&Color{}).fromAttribute(termboxCell.Bg)
+	// курсор мира показывается через MainViewport
+	if !ctx.layer.screenSpace {
+		x, y = ctx.viewports.main().toScreen(x, y)
+	}
 
-// 	cell := Cell{
-// 		Symbol:     termboxCell.Ch,
-// 		Foreground: foregroundColor,
-// 		Background: backgroundColor,
-// 	}
+	ctx.backend.SetCursor(x, y)
+}
 
-// 	return cell
-// }
+func (ctx *Context) HideCursor() {
+	ctx.backend.HideCursor()
+}
 
+// Flush собирает кадр и выводит только клетки, изменившиеся с прошлого вывода, а затем картинки PlaceImage
 func (ctx *Context) Flush() error {
-	err := termbox.Flush()
+	ctx.composeFrame()
+	ctx.prepareImages(ctx.renderer.clearPending)
+
+	err := ctx.renderer.present(ctx.backend, *ctx.defaultCell)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.presentImages()
 	if err != nil {
 		return err
 	}
@@ -328,13 +350,11 @@ func (ctx *Context) Flush() error {
 	return nil
 }
 
-// clear
-
-func (ctx *Context) Clear() error {
-	foregroundAttribute := ctx.defaultCell.Foreground.toAttribute()
-	backgroundAttribute := ctx.defaultCell.Background.toAttribute()
+// Redraw перерисовывает весь экран, например, если его содержимое испортил другой процесс
+func (ctx *Context) Redraw() error {
+	ctx.renderer.invalidate()
 
-	err := ctx.clear(foregroundAttribute, backgroundAttribute)
+	err := ctx.Flush()
 	if err != nil {
 		return err
 	}
@@ -342,99 +362,257 @@ func (ctx *Context) Clear() error {
 	return nil
 }
 
-func (ctx *Context) clear(foregroundAttribute, backgroundAttribute termbox.Attribute) error {
-	ctx.clearLocalScreen()
+// RenderStats возвращает статистику последнего Flush
+func (ctx *Context) RenderStats() RenderStats {
+	return ctx.renderer.stats
+}
 
-	err := ctx.clearTermboxScreen(foregroundAttribute, backgroundAttribute)
-	if err != nil {
-		return err
-	}
+// clear
+
+// Clear очищает слой, в который рисует ctx, а у Context из Sub - только его область
+func (ctx *Context) Clear() error {
+	ctx.clearLocalScreen()
 
 	return nil
 }
 
 func (ctx *Context) clearLocalScreen() {
-	*ctx.cells = nil
+	if !ctx.region.clipped {
+		ctx.layer.canvas.clear()
+		clear(ctx.layer.boxCells)
+		ctx.graphics.removeLayer(ctx.layer)
+
+		return
+	}
+
+	localRect, _ := ctx.Region()
+	ctx.clearImages(localRect)
+
+	// стираются только записанные клетки: область Sub может быть сколь угодно большой
+	for _, position := range ctx.layer.canvas.cellsIn(ctx.region.clip) {
+		ctx.eraseLayerCell(position.X, position.Y)
+	}
 }
 
-func (ctx *Context) clearTermboxScreen(foregroundAttribute, backgroundAttribute termbox.Attribute) error {
-	err := termbox.Clear(foregroundAttribute, backgroundAttribute)
-	if err != nil {
-		return err
+// ClearCell делает клетку пустой: в базовом слое это клетка по умолчанию, в остальных - прозрачная
+func (ctx *Context) ClearCell(x, y int) {
+	ctx.clearLocalCell(x, y)
+}
+
+func (ctx *Context) clearLocalCell(x, y int) {
+	ctx.clearImages(NewRect(x, y, 1, 1))
+
+	x, y, ok := ctx.region.toLayer(x, y)
+	if !ok {
+		return
 	}
 
-	return nil
+	ctx.eraseLayerCell(x, y)
 }
 
-func (ctx *Context) ClearRow(y int) {
-	ctx.clearTermboxRow(y)
+// eraseLayerCell стирает клетку в координатах слоя вместе с её связями с соседями
+func (ctx *Context) eraseLayerCell(x, y int) {
+	ctx.detachWideGlyph(x, y, false)
+	ctx.layer.forgetBoxCell(x, y)
 
+	ctx.layer.canvas.erase(x, y)
+}
+
+func (ctx *Context) ClearRow(y int) {
 	ctx.clearLocalRow(y)
 }
 
 func (ctx *Context) clearLocalRow(y int) {
-	maxY := len((*ctx.cells)) - 1
-	if y > maxY {
+	if !ctx.region.clipped {
+		ctx.layer.canvas.eraseRow(y)
+		ctx.layer.forgetBoxCells(func(_, boxY int) bool {
+			return boxY == y
+		})
+		ctx.clearImages(NewRect(everywhereRect.X, y, everywhereRect.Width, 1))
+
 		return
 	}
 
-	(*ctx.cells)[y] = nil
+	localRect, _ := ctx.Region()
+	for x := localRect.X; x < localRect.X+localRect.Width; x++ {
+		ctx.clearLocalCell(x, y)
+	}
+}
+
+func (ctx *Context) ClearColumn(x int) {
+	ctx.clearLocalColumn(x)
 }
 
-func (ctx *Context) clearTermboxRow(y int) {
-	maxY := len((*ctx.cells)) - 1
-	if y > maxY {
+func (ctx *Context) clearLocalColumn(x int) {
+	if !ctx.region.clipped {
+		// столбец может разрезать широкие символы, их половины по соседству заменяются пробелами
+		bounds, _ := ctx.layer.canvas.bounds()
+		for y := bounds.Y; y < bounds.Y+bounds.Height; y++ {
+			ctx.detachWideGlyph(x, y, false)
+		}
+
+		ctx.layer.canvas.eraseColumn(x)
+		ctx.layer.forgetBoxCells(func(boxX, _ int) bool {
+			return boxX == x
+		})
+		ctx.clearImages(NewRect(x, everywhereRect.Y, 1, everywhereRect.Height))
+
 		return
 	}
 
-	maxX := len((*ctx.cells)[y])
-	for x := range maxX {
-		ctx.setTermboxCell(x, y, *ctx.defaultCell)
+	localRect, _ := ctx.Region()
+	for y := localRect.Y; y < localRect.Y+localRect.Height; y++ {
+		ctx.clearLocalCell(x, y)
 	}
 }
 
-func (ctx *Context) ClearColumn(x int) {
-	ctx.clearTermboxColumn(x)
+// state
 
-	ctx.clearLocalColumn(x)
+func (ctx *Context) Abort() {
+	*ctx.handlerIndex = math.MaxInt - 1
 }
 
-func (ctx *Context) clearLocalColumn(x int) {
-	for y := range len((*ctx.cells)) {
-		maxX := len((*ctx.cells)[y]) - 1
-		if x > maxX {
-			continue
-		}
+func (ctx *Context) CurrentState() State {
+	ctx.stateMutex.RLock()
+	defer ctx.stateMutex.RUnlock()
+
+	state := ctx.getCurrentState()
+
+	return state
+}
+
+func (ctx *Context) PushState(state State) {
+	ctx.stateMutex.Lock()
+
+	previousState := ctx.getCurrentState()
+
+	*ctx.states = append(*ctx.states, state)
+	*ctx.stateIndex = len(*ctx.states) - 1
 
-		(*ctx.cells)[y][x] = *ctx.defaultCell
+	ctx.stateMutex.Unlock()
+
+	// обработчики вызываются после снятия блокировки, чтобы они сами могли менять стек
+	ctx.callStateHandlers(ctx.stateHandlers.pause[previousState], state)
+	ctx.callStateHandlers(ctx.stateHandlers.enter[state], previousState)
+}
+
+func (ctx *Context) PopState() (State, error) {
+	ctx.stateMutex.Lock()
+
+	// нижнее состояние стека не снимается, иначе обработчикам будет не из чего выбирать
+	if *ctx.stateIndex == 0 {
+		ctx.stateMutex.Unlock()
+
+		return NoState, ErrLastState
 	}
+
+	state := ctx.getCurrentState()
+
+	*ctx.states = (*ctx.states)[:*ctx.stateIndex]
+	*ctx.stateIndex = len(*ctx.states) - 1
+
+	nextState := ctx.getCurrentState()
+
+	ctx.stateMutex.Unlock()
+
+	ctx.callStateHandlers(ctx.stateHandlers.exit[state], nextState)
+	ctx.callStateHandlers(ctx.stateHandlers.resume[nextState], state)
+
+	return state, nil
 }
 
-func (ctx *Context) clearTermboxColumn(x int) {
-	for y := range len((*ctx.cells)) {
-		maxX := len((*ctx.cells)[y]) - 1
-		if x > maxX {
-			continue
-		}
+func (ctx *Context) SetState(state State) {
+	ctx.stateMutex.Lock()
 
-		ctx.setTermboxCell(x, y, *ctx.defaultCell)
+	previousState := ctx.getCurrentState()
+
+	(*ctx.states)[*ctx.stateIndex] = state
+
+	ctx.stateMutex.Unlock()
+
+	ctx.callStateHandlers(ctx.stateHandlers.exit[previousState], state)
+	ctx.callStateHandlers(ctx.stateHandlers.enter[state], previousState)
+}
+
+func (ctx *Context) States() []State {
+	ctx.stateMutex.RLock()
+	defer ctx.stateMutex.RUnlock()
+
+	states := make([]State, len(*ctx.states))
+	copy(states, *ctx.states)
+
+	return states
+}
+
+// ui goroutine
+
+// Do выполняет task в горутине Run и ждёт её завершения.
+// Если Do вызван в самой горутине Run, например из обработчика DispatchSerial, task вызывается сразу.
+// Из горутины, запущенной обработчиком, task ставится в очередь, даже если ctx получен в этом обработчике
+func (ctx *Context) Do(task Task) error {
+	if ctx.runGoroutine.Load() == goroutineID() {
+		task(ctx)
+
+		return nil
+	}
+
+	doneChannel := make(chan struct{})
+	ctx.Post(func(uiContext *Context) {
+		defer close(doneChannel)
+
+		task(uiContext)
+	})
+
+	select {
+	case <-doneChannel:
+		return nil
+	case <-ctx.stopChannel:
+		return ErrScreenStopped
 	}
 }
 
-// state
+// Post ставит task в очередь горутины Run и не ждёт её выполнения
+func (ctx *Context) Post(task Task) {
+	taskMessage := message{
+		task: task,
+	}
 
-func (ctx *Context) Abort() {
-	ctx.handlerIndex = math.MaxInt - 1
+	ctx.messages.push(taskMessage)
+}
+
+// Emit ставит событие в общую очередь, оно пройдёт через middlewares, обработчики состояния и postwares
+// так же, как события терминала. Можно вызывать из любой горутины
+func (ctx *Context) Emit(event Event) {
+	if event == nil {
+		return
+	}
+
+	eventMessage := message{
+		event: event,
+	}
+
+	ctx.messages.push(eventMessage)
 }
 
 // user util
 
 func (ctx *Context) Kill() {
-	ctx.killChannel <- struct{}{}
+	ctx.killOnce.Do(func() {
+		close(ctx.killChannel)
+	})
 }
 
 // util
 
+func (ctx *Context) isKilled() bool {
+	select {
+	case <-ctx.killChannel:
+		return true
+	default:
+		return false
+	}
+}
+
 func (ctx *Context) ViewSize() (int, int) {
 	return *ctx.viewSizeX, *ctx.viewSizeY
 }
@@ -442,6 +620,45 @@ func (ctx *Context) ViewSize() (int, int) {
 func (ctx *Context) setViewSize(x, y int) {
 	*ctx.viewSizeX = x
 	*ctx.viewSizeY = y
+
+	ctx.viewports.resize(x, y)
+	ctx.renderer.resize(x, y)
+}
+
+// composeFrame рисует в задний буфер кадра слои мира через каждую видимую область,
+// а поверх - экранные слои. Правая половина широкого символа без левой становится пробелом
+func (ctx *Context) composeFrame() {
+	back := ctx.renderer.back
+	back.fill(*ctx.defaultCell)
+
+	frameRect := NewRect(0, 0, back.width, back.height)
+	for _, current := range ctx.viewports.list {
+		visibleRect := current.rect.Intersect(frameRect)
+		for y := visibleRect.Y; y < visibleRect.Y+visibleRect.Height; y++ {
+			for x := visibleRect.X; x < visibleRect.X+visibleRect.Width; x++ {
+				worldX := x - current.rect.X + *current.cameraX
+				worldY := y - current.rect.Y + *current.cameraY
+
+				cell := ctx.layers.composeWorld(worldX, worldY, *ctx.defaultCell)
+				back.set(x, y, cell)
+			}
+		}
+	}
+
+	for y := range back.height {
+		for x := range back.width {
+			cell := ctx.layers.composeScreen(x, y, back.get(x, y))
+
+			// край видимой области, Paste или слой над левой половиной отрывают правую половину от широкого символа.
+			// Бэкенды её не рисуют, и в терминале остался бы прежний символ
+			if cell.Symbol == ContinuationSymbol && (x == 0 || !isWideCell(back.get(x-1, y))) {
+				cell.Symbol = DefaultSymbol
+				cell.Combining = ""
+			}
+
+			back.set(x, y, cell)
+		}
+	}
 }
 
 func (ctx *Context) getCurrentState() State {
@@ -450,21 +667,36 @@ func (ctx *Context) getCurrentState() State {
 	return state
 }
 
+// newUIContext запоминает текущую горутину как горутину Run
+func (ctx *Context) newUIContext() *Context {
+	ctx.runGoroutine.Store(goroutineID())
+
+	uiContext := *ctx
+
+	return &uiContext
+}
+
+func (ctx *Context) callStateHandlers(handlers []StateHandler, state State) {
+	for i := range handlers {
+		handlers[i](ctx, state)
+	}
+}
+
 func (ctx *Context) resetData(context *Context) {
 	if ctx.cancelFunc != nil {
 		ctx.cancelFunc()
 	}
 
 	ctx.cancelFunc = context.cancelFunc
-	ctx.handlerIndex = 0
+	*ctx.handlerIndex = 0
 }
 
 func (ctx *Context) addHandlerIndex() {
-	ctx.handlerIndex++
+	*ctx.handlerIndex++
 }
 
 func (ctx *Context) getHandlerIndex() int {
-	handlerIndex := ctx.handlerIndex
+	handlerIndex := *ctx.handlerIndex
 
 	return handlerIndex
 }
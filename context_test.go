@@ -0,0 +1,218 @@
+package gui_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/gggallahad/gui"
+	"github.com/gggallahad/gui/guitest"
+)
+
+func TestOverwriteWideGlyph(t *testing.T) {
+	tests := []struct {
+		name string
+		draw func(ctx *gui.Context)
+		want string
+	}{
+		{
+			name: "left and right halves",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(0, 0, "a", gui.DefaultColor, gui.DefaultColor)
+				ctx.SetCell(3, 0, gui.Cell{Symbol: 'b'})
+			},
+			want: "a  b  ",
+		},
+		{
+			name: "wide over wide shifted",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(1, 0, "字", gui.DefaultColor, gui.DefaultColor)
+			},
+			want: " 字   ",
+		},
+		{
+			name: "clear cell",
+			draw: func(ctx *gui.Context) {
+				ctx.ClearCell(1, 0)
+			},
+			want: "  字  ",
+		},
+		{
+			name: "clear column",
+			draw: func(ctx *gui.Context) {
+				ctx.ClearColumn(2)
+			},
+			want: "漢    ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := guitest.NewStarted(t, 6, 1)
+
+			h.Draw(t, func(ctx *gui.Context) {
+				ctx.SetText(0, 0, "漢字", gui.DefaultColor, gui.DefaultColor)
+				test.draw(ctx)
+			})
+
+			h.AssertString(t, test.want)
+
+			// у каждой правой половины широкого символа должна остаться левая
+			cells := h.Cells()
+			for x := range cells {
+				if cells[x].Symbol == gui.ContinuationSymbol && (x == 0 || gui.TextWidth(string(cells[x-1].Symbol)) != 2) {
+					t.Fatalf("orphan continuation cell at %d", x)
+				}
+			}
+		})
+	}
+}
+
+func TestOrphanContinuationBlanked(t *testing.T) {
+	d := gui.DefaultColor
+
+	tests := []struct {
+		name string
+		draw func(ctx *gui.Context)
+		want string
+	}{
+		{
+			name: "clipped by the screen edge",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(-1, 1, "漢xyz", d, d)
+			},
+			want: "    \n xyz",
+		},
+		{
+			name: "cut by a viewport border",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(0, 0, "漢", d, d)
+				err := ctx.AddViewport("right", gui.NewRect(2, 0, 2, 2), 1, 0)
+				if err != nil {
+					t.Error(err)
+				}
+			},
+			want: "漢  \n    ",
+		},
+		{
+			name: "left half covered by a layer",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(0, 0, "漢", d, d)
+				top, err := ctx.AddLayer("top", 1)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				top.SetCell(0, 0, gui.Cell{Symbol: '@'})
+			},
+			want: "@   \n    ",
+		},
+		{
+			name: "pasted from the middle of a glyph",
+			draw: func(ctx *gui.Context) {
+				ctx.SetText(0, 0, "漢字", d, d)
+				buffer := gui.CellBuffer{}
+				ctx.CopyRect(gui.NewRect(1, 0, 3, 1), &buffer)
+				ctx.Paste(0, 1, &buffer)
+			},
+			want: "漢字\n 字 ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := guitest.NewStarted(t, 4, 2)
+
+			h.Draw(t, test.draw)
+
+			h.AssertString(t, test.want)
+		})
+	}
+}
+
+func TestRedrawSyncsBackend(t *testing.T) {
+	h := guitest.NewStarted(t, 4, 1)
+
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetText(0, 0, "ab", gui.DefaultColor, gui.DefaultColor)
+	})
+
+	if h.Backend.Syncs() != 0 {
+		t.Fatalf("Flush synced the backend %d times", h.Backend.Syncs())
+	}
+
+	err := h.Screen.Do(func(ctx *gui.Context) {
+		ctx.Redraw()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// экран мог испортить другой процесс, поэтому Redraw не полагается на сравнение кадров
+	if h.Backend.Syncs() != 1 {
+		t.Fatalf("Redraw synced the backend %d times, want 1", h.Backend.Syncs())
+	}
+
+	h.AssertString(t, "ab  ")
+}
+
+func TestDoFromHandlerGoroutine(t *testing.T) {
+	h := guitest.NewStarted(t, 4, 1)
+
+	doneChannel := make(chan error, 1)
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		// ctx обработчика DispatchSerial, переданный в другую горутину, не должен рисовать мимо очереди
+		go func() {
+			doneChannel <- ctx.Do(func(uiContext *gui.Context) {
+				uiContext.SetCell(1, 0, gui.Cell{Symbol: 'g'})
+			})
+		}()
+
+		for range 1000 {
+			ctx.SetCell(0, 0, gui.Cell{Symbol: 'h'})
+		}
+	})
+
+	err := h.Symbol('x')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = <-doneChannel
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Draw(t, func(*gui.Context) {})
+	h.AssertString(t, "hg  ")
+}
+
+func TestDrawImageCentered(t *testing.T) {
+	h := guitest.NewStarted(t, 10, 4)
+
+	// картинка 2×4 вписывается в 10×4 клеток по высоте: 4×8 точек, то есть 4×4 клетки посередине
+	img := image.NewRGBA(image.Rect(0, 0, 2, 4))
+	for y := range 4 {
+		for x := range 2 {
+			img.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	var used gui.Rect
+	h.Draw(t, func(ctx *gui.Context) {
+		config := gui.ImageConfig{
+			Mode:       gui.PixelHalfBlock,
+			Dither:     false,
+			Stretch:    false,
+			Background: gui.DefaultColor,
+		}
+		used = ctx.DrawImage(gui.NewRect(0, 0, 10, 4), img, config)
+	})
+
+	want := gui.NewRect(3, 0, 4, 4)
+	if used != want {
+		t.Fatalf("DrawImage() = %v, want %v", used, want)
+	}
+
+	h.AssertString(t, "   ▀▀▀▀   \n   ▀▀▀▀   \n   ▀▀▀▀   \n   ▀▀▀▀   ")
+}
@@ -0,0 +1,21 @@
+package gui
+
+import "errors"
+
+var (
+	ErrLastState     error = errors.New("gui: cannot pop the last state")
+	ErrScreenStopped error = errors.New("gui: screen is stopped")
+	ErrInvalidColor  error = errors.New("gui: invalid color")
+	ErrInvalidMarkup error = errors.New("gui: invalid markup")
+	ErrLayerExists   error = errors.New("gui: layer already exists")
+	ErrLayerNotFound error = errors.New("gui: layer not found")
+	ErrBuiltinLayer  error = errors.New("gui: built-in layer cannot be removed")
+
+	ErrViewportExists   error = errors.New("gui: viewport already exists")
+	ErrViewportNotFound error = errors.New("gui: viewport not found")
+	ErrMainViewport     error = errors.New("gui: main viewport cannot be removed")
+
+	ErrGraphicsUnsupported error = errors.New("gui: terminal graphics are not supported")
+	ErrEmptyImage          error = errors.New("gui: image or rectangle is empty")
+	ErrImageNotFound       error = errors.New("gui: image not found")
+)
@@ -1,6 +1,6 @@
 package gui
 
-import "github.com/nsf/termbox-go"
+import "time"
 
 type (
 	Event interface {
@@ -23,6 +23,17 @@ type (
 		X int
 		Y int
 	}
+
+	EventTick struct {
+		ID   TimerID
+		Time time.Time
+	}
+
+	// элемент очереди горутины Run: либо событие, либо задача
+	message struct {
+		event Event
+		task  Task
+	}
 )
 
 func (e *EventKey) IsEvent() {
@@ -34,31 +45,5 @@ func (e *EventMouse) IsEvent() {
 func (e *EventResize) IsEvent() {
 }
 
-func termboxEventToEvent(termboxEvent termbox.Event) Event {
-	var event Event
-
-	switch termboxEvent.Type {
-	case termbox.EventKey:
-		eventKey := &EventKey{
-			Symbol:   termboxEvent.Ch,
-			Key:      KeyboardKey(termboxEvent.Key),
-			Modifier: Modifier(termboxEvent.Mod),
-		}
-		event = eventKey
-	case termbox.EventMouse:
-		eventMouse := &EventMouse{
-			X:   termboxEvent.MouseX,
-			Y:   termboxEvent.MouseY,
-			Key: MouseKey(termboxEvent.Key),
-		}
-		event = eventMouse
-	case termbox.EventResize:
-		eventResize := &EventResize{
-			X: termboxEvent.Width,
-			Y: termboxEvent.Height,
-		}
-		event = eventResize
-	}
-
-	return event
+func (e *EventTick) IsEvent() {
 }
@@ -16,10 +16,8 @@ type (
 	}
 
 	View struct {
-		CurrentX  int
-		CurrentY  int
-		PreviousX int
-		PreviousY int
+		CurrentX int
+		CurrentY int
 	}
 )
 
@@ -39,10 +37,8 @@ var (
 	}
 
 	view View = View{
-		CurrentX:  0,
-		CurrentY:  0,
-		PreviousX: 0,
-		PreviousY: 0,
+		CurrentX: 0,
+		CurrentY: 0,
 	}
 
 	setRowCell gui.Cell = gui.Cell{
@@ -77,6 +73,9 @@ var (
 
 	statusLineOffsetX int = 3
 	statusLineOffsetY int = 40
+
+	cursorLayer      string = "cursor"
+	cursorLayerIndex int    = 1
 )
 
 func main() {
@@ -97,7 +96,7 @@ func main() {
 
 	screen.BindGlobalMiddlewares(KillMiddleware)
 
-	screen.BindGlobalPostwares(DrawStatusLine, SetVariables)
+	screen.BindGlobalPostwares(DrawStatusLine)
 
 	screen.BindHandlers(gui.NoState, NoStateHandler)
 
@@ -110,6 +109,11 @@ func InitHandler(ctx *gui.Context) {
 		return
 	}
 
+	_, err = ctx.AddLayer(cursorLayer, cursorLayerIndex)
+	if err != nil {
+		return
+	}
+
 	drawCursorPosition(ctx)
 	DrawStatusLine(ctx, nil)
 }
@@ -185,25 +189,28 @@ func MoveCursor(ctx *gui.Context, cursorPositionOffsetX, cursorPositionOffsetY i
 	drawCursorPosition(ctx)
 }
 
+// курсор живёт в своём слое, поэтому его стирание открывает то, что было нарисовано под ним
 func clearCursorPosition(ctx *gui.Context) {
-	ctx.SetCell(cursor.X, cursor.Y, gui.DefaultCell)
+	cursorContext, err := ctx.Layer(cursorLayer)
+	if err != nil {
+		return
+	}
+
+	cursorContext.ClearCell(cursor.X, cursor.Y)
 }
 
 func drawCursorPosition(ctx *gui.Context) {
-	ctx.SetCell(cursor.X, cursor.Y, cursor.Cell)
+	cursorContext, err := ctx.Layer(cursorLayer)
+	if err != nil {
+		return
+	}
+
+	cursorContext.SetCell(cursor.X, cursor.Y, cursor.Cell)
 }
 
 func updateCursorPosition(cursorPositionOffsetX, cursorPositionOffsetY int) {
 	cursor.X += cursorPositionOffsetX
 	cursor.Y += cursorPositionOffsetY
-
-	if cursor.X < 0 {
-		cursor.X = 0
-	}
-
-	if cursor.Y < 0 {
-		cursor.Y = 0
-	}
 }
 
 func MoveCamera(ctx *gui.Context, viewPositionOffsetX, viewPositionOffsetY int) error {
@@ -219,13 +226,6 @@ func MoveCamera(ctx *gui.Context, viewPositionOffsetX, viewPositionOffsetY int)
 func updateViewPosition(viewPositionOffsetX, viewPositionOffsetY int) {
 	view.CurrentX += viewPositionOffsetX
 	view.CurrentY += viewPositionOffsetY
-
-	if view.CurrentX < 0 {
-		view.CurrentX = 0
-	}
-	if view.CurrentY < 0 {
-		view.CurrentY = 0
-	}
 }
 
 func updateViewContent(ctx *gui.Context) error {
@@ -239,7 +239,7 @@ func updateViewContent(ctx *gui.Context) error {
 }
 
 func SetRow(ctx *gui.Context) {
-	rowCells := make([]gui.Cell, 0, cursor.X)
+	rowCells := make([]gui.Cell, 0, max(cursor.X, 0))
 	for range cursor.X {
 		rowCells = append(rowCells, setRowCell)
 	}
@@ -250,7 +250,7 @@ func SetRow(ctx *gui.Context) {
 }
 
 func SetColumn(ctx *gui.Context) {
-	columnCells := make([]gui.Cell, 0, cursor.Y)
+	columnCells := make([]gui.Cell, 0, max(cursor.Y, 0))
 	for range cursor.Y {
 		columnCells = append(columnCells, setColumnCell)
 	}
@@ -265,23 +265,23 @@ func SetText(ctx *gui.Context) {
 }
 
 func DrawStatusLine(ctx *gui.Context, eventType gui.Event) {
-	ctx.ClearRow(view.PreviousY + statusLineOffsetY)
+	// строка состояния рисуется в экранных координатах и не двигается вместе с камерой
+	hud := ctx.HUD()
+	hud.ClearRow(statusLineOffsetY)
 
 	spaceBetweenTypesCount := 5
 	spaceBetweenElementsCount := 3
 	spaceBetweenTypesString := strings.Repeat(" ", spaceBetweenTypesCount)
 	spaceBetweenElementsString := strings.Repeat(" ", spaceBetweenElementsCount)
-	text := fmt.Sprintf("cursorX: %d%scursorY: %d%scameraX: %d%scameraY: %d", cursor.X, spaceBetweenElementsString, cursor.Y, spaceBetweenTypesString, view.CurrentX, spaceBetweenElementsString, view.CurrentY)
-
-	ctx.SetText(view.CurrentX+statusLineOffsetX, view.CurrentY+statusLineOffsetY, text, statusLineForeground, statusLineBackground)
+	markup := fmt.Sprintf("[bold]cursorX:[/] %d%s[bold]cursorY:[/] %d%s[bold]cameraX:[/] %d%s[bold]cameraY:[/] %d", cursor.X, spaceBetweenElementsString, cursor.Y, spaceBetweenTypesString, view.CurrentX, spaceBetweenElementsString, view.CurrentY)
 
-	err := ctx.Flush()
+	_, err := hud.SetMarkup(statusLineOffsetX, statusLineOffsetY, markup, statusLineForeground, statusLineBackground)
 	if err != nil {
 		return
 	}
-}
 
-func SetVariables(ctx *gui.Context, eventType gui.Event) {
-	view.PreviousX = view.CurrentX
-	view.PreviousY = view.CurrentY
+	err = ctx.Flush()
+	if err != nil {
+		return
+	}
 }
@@ -3,7 +3,8 @@ module github.com/gggallahad/gui
 go 1.23.1
 
 require (
-	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/nsf/termbox-go v1.1.1
-	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/rivo/uniseg v0.4.7
 )
+
+require github.com/mattn/go-runewidth v0.0.16 // indirect
@@ -0,0 +1,398 @@
+package gui
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type (
+	// GraphicsProtocol - способ вывода настоящих картинок поверх клеток
+	GraphicsProtocol int
+
+	ImageID uint32
+
+	// RawWriter - необязательный интерфейс Backend для вывода управляющих последовательностей в обход клеток.
+	// Без него картинки не выводятся, см. PlaceImage
+	RawWriter interface {
+		WriteRaw(data []byte) error
+	}
+
+	// imagePlacement - картинка, привязанная к прямоугольнику клеток слоя
+	imagePlacement struct {
+		id    ImageID
+		layer *layer
+		rect  Rect
+		// закодированная картинка: для Kitty - команда передачи с номером картинки, для Sixel - сама картинка
+		encoded []byte
+		// в Kitty данные передаются один раз, дальше картинка только показывается и переносится
+		transmitted bool
+
+		shown      bool
+		screenRect Rect
+	}
+
+	// graphics хранит картинки и то, что нужно стереть с экрана при следующем Flush
+	graphics struct {
+		protocol   GraphicsProtocol
+		cellWidth  int
+		cellHeight int
+
+		lastID     ImageID
+		placements []*imagePlacement
+
+		pendingDeletes []byte
+		// картинку Sixel нельзя удалить командой: её стирают строки экрана, выведенные заново
+		staleRects []Rect
+	}
+)
+
+const (
+	// GraphicsAuto выбирает протокол по переменным окружения, см. DetectGraphicsProtocol
+	GraphicsAuto GraphicsProtocol = iota
+	GraphicsNone
+	GraphicsKitty
+	GraphicsSixel
+)
+
+const (
+	// размер клетки в точках, если он не задан в ScreenConfig
+	defaultCellPixelWidth  int = 10
+	defaultCellPixelHeight int = 20
+)
+
+var (
+	// прямоугольник, в который попадают все клетки слоя
+	everywhereRect Rect = NewRect(math.MinInt/4, math.MinInt/4, math.MaxInt/2, math.MaxInt/2)
+)
+
+// DetectGraphicsProtocol определяет протокол картинок по TERM, TERM_PROGRAM и KITTY_WINDOW_ID.
+// Терминал не опрашивается: ответ на запрос пришёл бы вперемешку с событиями ввода
+func DetectGraphicsProtocol() GraphicsProtocol {
+	term := strings.ToLower(os.Getenv("TERM"))
+	termProgram := strings.ToLower(os.Getenv("TERM_PROGRAM"))
+
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || strings.Contains(term, "kitty"):
+		return GraphicsKitty
+	case termProgram == "wezterm" || termProgram == "ghostty":
+		return GraphicsKitty
+	case strings.Contains(term, "sixel") || strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm"):
+		return GraphicsSixel
+	case termProgram == "mintty" || termProgram == "iterm.app":
+		return GraphicsSixel
+	default:
+		return GraphicsNone
+	}
+}
+
+func newGraphics() *graphics {
+	g := graphics{
+		protocol:       GraphicsNone,
+		cellWidth:      defaultCellPixelWidth,
+		cellHeight:     defaultCellPixelHeight,
+		lastID:         0,
+		placements:     nil,
+		pendingDeletes: nil,
+		staleRects:     nil,
+	}
+
+	return &g
+}
+
+// remove убирает картинки слоя, задевающие rect в координатах слоя, и запоминает, что их нужно стереть
+func (g *graphics) remove(layer *layer, rect Rect) {
+	kept := g.placements[:0]
+	for _, placement := range g.placements {
+		if placement.layer != layer || placement.rect.Intersect(rect).Empty() {
+			kept = append(kept, placement)
+			continue
+		}
+
+		g.delete(placement)
+	}
+
+	clear(g.placements[len(kept):])
+	g.placements = kept
+}
+
+func (g *graphics) removeLayer(layer *layer) {
+	g.remove(layer, everywhereRect)
+}
+
+// hide стирает показанную картинку: в Kitty - командой, которая оставляет данные картинки в терминале,
+// в Sixel - повторным выводом строк экрана под ней
+func (g *graphics) hide(placement *imagePlacement) {
+	if !placement.shown {
+		return
+	}
+
+	switch g.protocol {
+	case GraphicsKitty:
+		g.pendingDeletes = append(g.pendingDeletes, kittyHide(placement.id)...)
+	case GraphicsSixel:
+		g.staleRects = append(g.staleRects, placement.screenRect)
+	}
+
+	placement.shown = false
+}
+
+// delete стирает картинку, которая больше не понадобится. В Kitty вместе с ней удаляются и её данные
+func (g *graphics) delete(placement *imagePlacement) {
+	if g.protocol == GraphicsKitty && placement.transmitted {
+		g.pendingDeletes = append(g.pendingDeletes, kittyDelete(placement.id)...)
+		placement.shown = false
+
+		return
+	}
+
+	g.hide(placement)
+}
+
+// encode вписывает img в rect с сохранением пропорций и кодирует его для протокола.
+// Возвращает закодированную картинку и клетки, которые она займёт
+func (g *graphics) encode(img image.Image, rect Rect, id ImageID) ([]byte, Rect) {
+	imageBounds := img.Bounds()
+
+	maxWidth := rect.Width * g.cellWidth
+	maxHeight := rect.Height * g.cellHeight
+
+	imageWidth := float64(imageBounds.Dx())
+	imageHeight := float64(imageBounds.Dy())
+	scale := math.Min(float64(maxWidth)/imageWidth, float64(maxHeight)/imageHeight)
+
+	width := min(max(int(math.Round(imageWidth*scale)), 1), maxWidth)
+	height := min(max(int(math.Round(imageHeight*scale)), 1), maxHeight)
+
+	usedWidth := (width + g.cellWidth - 1) / g.cellWidth
+	usedHeight := (height + g.cellHeight - 1) / g.cellHeight
+	usedRect := NewRect(rect.X+(rect.Width-usedWidth)/2, rect.Y+(rect.Height-usedHeight)/2, usedWidth, usedHeight)
+
+	scaled := scaleImage(img, width, height)
+
+	if g.protocol == GraphicsKitty {
+		return EncodeKitty(scaled, id), usedRect
+	}
+
+	return EncodeSixel(scaled), usedRect
+}
+
+// scaleImage пересчитывает img в width×height точек так же, как DrawImage
+func scaleImage(img image.Image, width, height int) *image.NRGBA {
+	dots := sampleImage(img, width, height, DefaultColor)
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i, dot := range dots {
+		if !dot.visible {
+			continue
+		}
+
+		color := dot.color()
+		offset := i * 4
+		scaled.Pix[offset] = uint8(color.R)
+		scaled.Pix[offset+1] = uint8(color.G)
+		scaled.Pix[offset+2] = uint8(color.B)
+		scaled.Pix[offset+3] = 0xff
+	}
+
+	return scaled
+}
+
+// images
+
+// PlaceImage показывает img поверх клеток rect, вписав его с сохранением пропорций.
+// Картинка привязана к слою ctx: она двигается вместе с видимой областью и стирается, когда Clear, ClearCell,
+// ClearRow или ClearColumn задевают её клетки. Картинки всегда лежат над клетками всех слоёв.
+// Возвращает ErrGraphicsUnsupported, если терминал или бэкенд не умеют выводить картинки
+func (ctx *Context) PlaceImage(rect Rect, img image.Image) (ImageID, error) {
+	if ctx.graphics.protocol != GraphicsKitty && ctx.graphics.protocol != GraphicsSixel {
+		return 0, ErrGraphicsUnsupported
+	}
+
+	rect.X += ctx.region.originX
+	rect.Y += ctx.region.originY
+	if ctx.region.clipped {
+		rect = rect.Intersect(ctx.region.clip)
+	}
+
+	if rect.Empty() || img.Bounds().Empty() {
+		return 0, ErrEmptyImage
+	}
+
+	ctx.graphics.lastID++
+	id := ctx.graphics.lastID
+
+	encoded, usedRect := ctx.graphics.encode(img, rect, id)
+
+	placement := imagePlacement{
+		id:      id,
+		layer:   ctx.layer,
+		rect:    usedRect,
+		encoded: encoded,
+	}
+	ctx.graphics.placements = append(ctx.graphics.placements, &placement)
+
+	return id, nil
+}
+
+// DeleteImage убирает картинку id с экрана при следующем Flush
+func (ctx *Context) DeleteImage(id ImageID) error {
+	for i, placement := range ctx.graphics.placements {
+		if placement.id != id {
+			continue
+		}
+
+		ctx.graphics.delete(placement)
+		ctx.graphics.placements = append(ctx.graphics.placements[:i], ctx.graphics.placements[i+1:]...)
+
+		return nil
+	}
+
+	return ErrImageNotFound
+}
+
+// GraphicsProtocol возвращает протокол картинок, выбранный при Init
+func (ctx *Context) GraphicsProtocol() GraphicsProtocol {
+	return ctx.graphics.protocol
+}
+
+// clearImages убирает картинки слоя ctx, задевающие localRect
+func (ctx *Context) clearImages(localRect Rect) {
+	if len(ctx.graphics.placements) == 0 {
+		return
+	}
+
+	layerRect := localRect
+	layerRect.X += ctx.region.originX
+	layerRect.Y += ctx.region.originY
+	if ctx.region.clipped {
+		layerRect = layerRect.Intersect(ctx.region.clip)
+	}
+
+	ctx.graphics.remove(ctx.layer, layerRect)
+}
+
+// flush
+
+// prepareImages решает, какие картинки видны после сдвига видимой области, и стирает остальные.
+// Вызывается до вывода клеток: строки под стёртой картинкой Sixel renderer выводит заново через Backend.SyncRows.
+// Бэкенды вроде termbox сравнивают клетки со своим кадром, поэтому обычный вывод тех же клеток картинку не сотрёт.
+// Каждый сдвиг видимой области с картинкой Sixel стоит повторного вывода её строк, картинка Kitty только переносится
+func (ctx *Context) prepareImages(fullRedraw bool) {
+	screenRect := NewRect(0, 0, *ctx.viewSizeX, *ctx.viewSizeY)
+
+	for _, placement := range ctx.graphics.placements {
+		// очистка экрана стирает картинки в терминале, поэтому они выводятся заново
+		if fullRedraw {
+			placement.shown = false
+		}
+
+		placementScreenRect := placement.rect
+		if !placement.layer.screenSpace {
+			placementScreenRect.X, placementScreenRect.Y = ctx.viewports.main().toScreen(placement.rect.X, placement.rect.Y)
+		}
+
+		if !placement.shown || placementScreenRect == placement.screenRect {
+			placement.screenRect = placementScreenRect
+			continue
+		}
+
+		ctx.graphics.hide(placement)
+
+		placement.screenRect = placementScreenRect
+	}
+
+	// картинка выводится, только если целиком помещается на экран: обрезать её по клеткам протоколы не умеют
+	for _, placement := range ctx.graphics.placements {
+		visible := placement.layer.visible && placement.screenRect.Intersect(screenRect) == placement.screenRect
+		if visible || !placement.shown {
+			continue
+		}
+
+		ctx.graphics.hide(placement)
+	}
+
+	staleRects := ctx.graphics.staleRects
+	ctx.graphics.staleRects = nil
+
+	if fullRedraw {
+		return
+	}
+
+	for _, staleRect := range staleRects {
+		// строки выводятся целиком, чтобы не разорвать широкий символ на краю картинки
+		rowsRect := NewRect(0, staleRect.Y, screenRect.Width, staleRect.Height).Intersect(screenRect)
+		if rowsRect.Empty() {
+			continue
+		}
+
+		ctx.renderer.syncRows = append(ctx.renderer.syncRows, rowsRect)
+
+		// вместе со строками стираются и другие картинки Sixel в них, поэтому они выводятся заново
+		for _, placement := range ctx.graphics.placements {
+			if !placement.screenRect.Intersect(rowsRect).Empty() {
+				placement.shown = false
+			}
+		}
+	}
+}
+
+// presentImages выводит стирания и ещё не показанные картинки после вывода клеток
+func (ctx *Context) presentImages() error {
+	rawWriter, ok := ctx.backend.(RawWriter)
+	if !ok {
+		ctx.graphics.pendingDeletes = nil
+
+		return nil
+	}
+
+	screenRect := NewRect(0, 0, *ctx.viewSizeX, *ctx.viewSizeY)
+
+	buffer := bytes.Buffer{}
+	buffer.Write(ctx.graphics.pendingDeletes)
+	ctx.graphics.pendingDeletes = nil
+
+	for _, placement := range ctx.graphics.placements {
+		visible := placement.layer.visible && placement.screenRect.Intersect(screenRect) == placement.screenRect
+		if placement.shown || !visible {
+			continue
+		}
+
+		// данные Kitty передаются один раз, до первого показа
+		if ctx.graphics.protocol == GraphicsKitty && !placement.transmitted {
+			buffer.Write(placement.encoded)
+			placement.transmitted = true
+		}
+
+		// курсор сохраняется, чтобы бэкенд не потерял свою позицию после вывода картинки
+		buffer.WriteString("\x1b7\x1b[")
+		buffer.WriteString(strconv.Itoa(placement.screenRect.Y + 1))
+		buffer.WriteByte(';')
+		buffer.WriteString(strconv.Itoa(placement.screenRect.X + 1))
+		buffer.WriteByte('H')
+		if ctx.graphics.protocol == GraphicsKitty {
+			buffer.Write(kittyPlace(placement.id, placement.screenRect.Width, placement.screenRect.Height))
+		} else {
+			buffer.Write(placement.encoded)
+		}
+		buffer.WriteString("\x1b8")
+
+		placement.shown = true
+	}
+
+	if buffer.Len() == 0 {
+		return nil
+	}
+
+	ctx.renderer.stats.EstimatedBytes += buffer.Len()
+
+	err := rawWriter.WriteRaw(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
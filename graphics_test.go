@@ -0,0 +1,137 @@
+package gui_test
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+
+	"github.com/gggallahad/gui"
+	"github.com/gggallahad/gui/guitest"
+)
+
+// redImage - картинка 2×4 точки, которая при клетке 1×2 точки занимает ровно 2×2 клетки
+func redImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 4))
+	for y := range 4 {
+		for x := range 2 {
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+
+	return img
+}
+
+func newGraphicsHarness(t *testing.T, protocol gui.GraphicsProtocol) *guitest.Harness {
+	t.Helper()
+
+	return guitest.NewStarted(t, 6, 4, gui.ScreenConfig{Graphics: protocol, CellPixelWidth: 1, CellPixelHeight: 2})
+}
+
+// flushRaw рисует и возвращает то, что ушло в терминал через WriteRaw
+func flushRaw(t *testing.T, h *guitest.Harness, draw gui.Task) string {
+	t.Helper()
+
+	h.Backend.ResetRaw()
+	h.Draw(t, draw)
+
+	return string(h.Backend.Raw())
+}
+
+func placed(row, column string, command string) string {
+	return "\x1b7\x1b[" + row + ";" + column + "H" + command + "\x1b8"
+}
+
+func TestKittyImageLifecycle(t *testing.T) {
+	h := newGraphicsHarness(t, gui.GraphicsKitty)
+
+	var id gui.ImageID
+	transmitted := string(gui.EncodeKitty(redImage(), 1))
+	place := "\x1b_Ga=p,i=1,p=1,c=2,r=2,C=1,q=2\x1b\\"
+	hidden := "\x1b_Ga=d,d=i,i=1,q=2\x1b\\"
+	deleted := "\x1b_Ga=d,d=I,i=1,q=2\x1b\\"
+
+	raw := flushRaw(t, h, func(ctx *gui.Context) {
+		var err error
+		id, err = ctx.PlaceImage(gui.NewRect(1, 1, 2, 2), redImage())
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	if want := transmitted + placed("2", "2", place); raw != want {
+		t.Fatalf("place wrote\n%q\nwant\n%q", raw, want)
+	}
+
+	raw = flushRaw(t, h, func(ctx *gui.Context) {})
+	if raw != "" {
+		t.Fatalf("unchanged frame wrote %q", raw)
+	}
+
+	// после сдвига видимой области картинка переносится без повторной передачи данных
+	raw = flushRaw(t, h, func(ctx *gui.Context) {
+		ctx.SetViewPosition(1, 0)
+	})
+	if want := hidden + placed("2", "1", place); raw != want {
+		t.Fatalf("scroll wrote\n%q\nwant\n%q", raw, want)
+	}
+
+	raw = flushRaw(t, h, func(ctx *gui.Context) {
+		err := ctx.DeleteImage(id)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	if raw != deleted {
+		t.Fatalf("delete wrote %q, want %q", raw, deleted)
+	}
+
+	if h.Backend.Syncs() != 0 || len(h.Backend.SyncedRows()) != 0 {
+		t.Fatalf("Kitty images synced the backend: %d times, rows %v", h.Backend.Syncs(), h.Backend.SyncedRows())
+	}
+}
+
+func TestSixelImageLifecycle(t *testing.T) {
+	h := newGraphicsHarness(t, gui.GraphicsSixel)
+
+	encoded := string(gui.EncodeSixel(redImage()))
+	imageRows := gui.NewRect(0, 1, 6, 2)
+
+	raw := flushRaw(t, h, func(ctx *gui.Context) {
+		_, err := ctx.PlaceImage(gui.NewRect(1, 1, 2, 2), redImage())
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	if want := placed("2", "2", encoded); raw != want {
+		t.Fatalf("place wrote\n%q\nwant\n%q", raw, want)
+	}
+	if len(h.Backend.SyncedRows()) != 0 {
+		t.Fatalf("placing an image synced rows %v", h.Backend.SyncedRows())
+	}
+
+	// Sixel нельзя удалить командой: старую картинку стирают строки, выведенные заново, новая выводится после них
+	raw = flushRaw(t, h, func(ctx *gui.Context) {
+		ctx.SetViewPosition(1, 0)
+	})
+	if want := placed("2", "1", encoded); raw != want {
+		t.Fatalf("scroll wrote\n%q\nwant\n%q", raw, want)
+	}
+	if !slices.Equal(h.Backend.SyncedRows(), []gui.Rect{imageRows}) {
+		t.Fatalf("scroll synced rows %v, want %v", h.Backend.SyncedRows(), imageRows)
+	}
+
+	raw = flushRaw(t, h, func(ctx *gui.Context) {
+		ctx.ClearCell(2, 2)
+	})
+	if raw != "" {
+		t.Fatalf("delete wrote %q", raw)
+	}
+	if !slices.Equal(h.Backend.SyncedRows(), []gui.Rect{imageRows, imageRows}) {
+		t.Fatalf("delete synced rows %v", h.Backend.SyncedRows())
+	}
+
+	// весь экран выводится заново только через Redraw
+	if h.Backend.Syncs() != 0 {
+		t.Fatalf("Sixel images synced the whole screen %d times", h.Backend.Syncs())
+	}
+}
@@ -0,0 +1,215 @@
+package guitest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gggallahad/gui"
+)
+
+type (
+	style struct {
+		Foreground gui.Color
+		Background gui.Color
+		Attributes gui.Attribute
+	}
+
+	attributeName struct {
+		attribute gui.Attribute
+		name      string
+	}
+)
+
+var (
+	updateGolden *bool = flag.Bool("update-golden", false, "rewrite testdata/*.golden files with the current snapshots")
+
+	styleKeys []rune = []rune("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+	attributeNames []attributeName = []attributeName{
+		{gui.AttrBold, "bold"},
+		{gui.AttrItalic, "italic"},
+		{gui.AttrUnderline, "underline"},
+		{gui.AttrReverse, "reverse"},
+		{gui.AttrBlink, "blink"},
+		{gui.AttrStrikethrough, "strikethrough"},
+		{gui.AttrDim, "dim"},
+	}
+)
+
+const (
+	goldenDirectory string = "testdata"
+	goldenExtension string = ".golden"
+)
+
+// Snapshot возвращает экран на момент последнего Flush в текстовом виде:
+// сетка символов, сетка ключей стилей и расшифровка ключей в порядке первого появления.
+// Строки обрамлены '|', чтобы пробелы в конце строки не терялись
+func Snapshot(backend *gui.MemoryBackend) string {
+	width, height := backend.Size()
+	cells := backend.Cells()
+
+	styles := make(map[style]rune)
+	legend := make([]style, 0)
+
+	glyphsBuilder := strings.Builder{}
+	stylesBuilder := strings.Builder{}
+	for y := range height {
+		glyphsBuilder.WriteByte('|')
+		stylesBuilder.WriteByte('|')
+
+		for x := range width {
+			cell := cells[y*width+x]
+
+			// правая половина широкого символа не выводится, чтобы строка совпадала с тем, что видно в терминале
+			switch cell.Symbol {
+			case gui.ContinuationSymbol:
+			case 0:
+				glyphsBuilder.WriteRune(gui.DefaultSymbol)
+			default:
+				glyphsBuilder.WriteRune(cell.Symbol)
+				glyphsBuilder.WriteString(cell.Combining)
+			}
+
+			cellStyle := style{
+				Foreground: cell.Foreground,
+				Background: cell.Background,
+				Attributes: cell.Attributes,
+			}
+			key, ok := styles[cellStyle]
+			if !ok {
+				key = styleKey(len(legend))
+				styles[cellStyle] = key
+				legend = append(legend, cellStyle)
+			}
+			stylesBuilder.WriteRune(key)
+		}
+
+		glyphsBuilder.WriteString("|\n")
+		stylesBuilder.WriteString("|\n")
+	}
+
+	snapshot := strings.Builder{}
+	fmt.Fprintf(&snapshot, "size %dx%d\n", width, height)
+	snapshot.WriteString("glyphs:\n")
+	snapshot.WriteString(glyphsBuilder.String())
+	snapshot.WriteString("styles:\n")
+	snapshot.WriteString(stylesBuilder.String())
+	snapshot.WriteString("legend:\n")
+	for i := range legend {
+		fmt.Fprintf(&snapshot, "%c %s\n", styleKey(i), legend[i].String())
+	}
+
+	return snapshot.String()
+}
+
+func (h *Harness) Snapshot() string {
+	return Snapshot(h.Backend)
+}
+
+// AssertGolden сравнивает snapshot с testdata/<name>.golden.
+// С флагом -update-golden файл перезаписывается текущим snapshot
+func AssertGolden(t testing.TB, name string, snapshot string) {
+	t.Helper()
+
+	path := filepath.Join(goldenDirectory, name+goldenExtension)
+
+	if *updateGolden {
+		err := os.MkdirAll(goldenDirectory, 0o755)
+		if err != nil {
+			t.Fatalf("guitest: create %s: %v", goldenDirectory, err)
+		}
+
+		err = os.WriteFile(path, []byte(snapshot), 0o644)
+		if err != nil {
+			t.Fatalf("guitest: write %s: %v", path, err)
+		}
+
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("guitest: read %s: %v (run with -update-golden to create it)", path, err)
+	}
+
+	want := string(golden)
+	if want == snapshot {
+		return
+	}
+
+	t.Errorf("guitest: snapshot differs from %s:\n%s", path, diffLines(want, snapshot))
+}
+
+func (h *Harness) AssertGolden(t testing.TB, name string) {
+	t.Helper()
+
+	AssertGolden(t, name, h.Snapshot())
+}
+
+// util
+
+func (s style) String() string {
+	text := fmt.Sprintf("fg=%s bg=%s", colorString(s.Foreground), colorString(s.Background))
+	if s.Attributes != gui.NoAttributes {
+		text += " attr=" + attributesString(s.Attributes)
+	}
+
+	return text
+}
+
+func attributesString(attributes gui.Attribute) string {
+	names := make([]string, 0)
+	for i := range attributeNames {
+		if attributes.Has(attributeNames[i].attribute) {
+			names = append(names, attributeNames[i].name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+func colorString(color gui.Color) string {
+	if color == gui.DefaultColor {
+		return "default"
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", color.R, color.G, color.B)
+}
+
+func styleKey(index int) rune {
+	if index < len(styleKeys) {
+		return styleKeys[index]
+	}
+
+	// после латиницы и цифр берутся символы из Latin-1 Supplement и дальше
+	return rune(0xC0 + index - len(styleKeys))
+}
+
+// построчный diff без выравнивания: достаточно, чтобы увидеть, какие строки экрана изменились
+func diffLines(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	builder := strings.Builder{}
+	for i := range max(len(wantLines), len(gotLines)) {
+		var wantLine, gotLine string
+		if i < len(wantLines) {
+			wantLine = wantLines[i]
+		}
+		if i < len(gotLines) {
+			gotLine = gotLines[i]
+		}
+
+		if wantLine == gotLine {
+			continue
+		}
+
+		fmt.Fprintf(&builder, "line %d:\n- %s\n+ %s\n", i+1, wantLine, gotLine)
+	}
+
+	return builder.String()
+}
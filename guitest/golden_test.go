@@ -0,0 +1,328 @@
+package guitest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gggallahad/gui"
+)
+
+func TestGoldenRowsAndColumns(t *testing.T) {
+	h := NewStarted(t, 8, 5)
+
+	red := gui.Color{R: 255}
+	blue := gui.Color{B: 255}
+
+	h.Draw(t, func(ctx *gui.Context) {
+		rowCell := gui.Cell{Symbol: '-', Foreground: red, Background: gui.DefaultColor}
+		ctx.SetRow(1, []gui.Cell{rowCell, rowCell, rowCell, rowCell, rowCell})
+
+		columnCell := gui.Cell{Symbol: '|', Foreground: blue, Background: gui.DefaultColor, Attributes: gui.AttrBold}
+		ctx.SetColumn(6, []gui.Cell{columnCell, columnCell, columnCell, columnCell})
+
+		ctx.SetText(0, 3, "漢a", gui.DefaultColor, gui.DefaultColor, gui.AttrUnderline)
+
+		// SetRow заменяет строку целиком, поэтому '|' в строке 1 пропадает
+		ctx.SetRow(1, []gui.Cell{rowCell, rowCell})
+
+		ctx.UpdateViewContent()
+	})
+
+	h.AssertGolden(t, "rows_columns")
+}
+
+func TestGoldenViewOffset(t *testing.T) {
+	h := NewStarted(t, 6, 4)
+
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetText(-3, -2, "neg", gui.DefaultColor, gui.DefaultColor)
+		ctx.SetText(0, 0, "origin", gui.DefaultColor, gui.DefaultColor)
+		ctx.SetText(4, 3, "far", gui.DefaultColor, gui.DefaultColor)
+
+		ctx.HUD().SetText(0, 3, "H", gui.DefaultColor, gui.DefaultColor)
+
+		ctx.SetViewPosition(-3, -2)
+	})
+
+	h.AssertGolden(t, "view_offset")
+
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetViewPosition(2, 1)
+	})
+
+	h.AssertGolden(t, "view_offset_scrolled")
+}
+
+func TestUpdateGoldenRewritesFile(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updateGoldenValue := *updateGolden
+	*updateGolden = true
+	t.Cleanup(func() {
+		*updateGolden = updateGoldenValue
+		os.Chdir(workingDirectory)
+	})
+
+	path := filepath.Join(goldenDirectory, "rewrite"+goldenExtension)
+	for _, snapshot := range []string{"first\n", "second\n"} {
+		AssertGolden(t, "rewrite", snapshot)
+
+		written, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(written) != snapshot {
+			t.Fatalf("golden file contains %q, want %q", written, snapshot)
+		}
+	}
+
+	// без флага файл только сравнивается
+	*updateGolden = false
+
+	AssertGolden(t, "rewrite", "second\n")
+}
+
+func TestGoldenStateStack(t *testing.T) {
+	const (
+		stateDialog gui.State = iota + 1
+		stateConfirm
+	)
+
+	h, err := New(12, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	names := map[gui.State]string{
+		gui.NoState:  "main",
+		stateDialog:  "dialog",
+		stateConfirm: "confirm",
+	}
+
+	// каждое состояние рисует себя в строке своей глубины при входе и стирает её при выходе
+	calls := []string{}
+	for state, name := range names {
+		h.Screen.BindEnterHandlers(state, func(ctx *gui.Context, previous gui.State) {
+			calls = append(calls, "enter "+name+" from "+names[previous])
+			ctx.SetText(0, len(ctx.States())-1, name, gui.DefaultColor, gui.DefaultColor)
+		})
+		h.Screen.BindExitHandlers(state, func(ctx *gui.Context, next gui.State) {
+			calls = append(calls, "exit "+name+" to "+names[next])
+			ctx.ClearRow(len(ctx.States()))
+		})
+		h.Screen.BindPauseHandlers(state, func(ctx *gui.Context, pushed gui.State) {
+			calls = append(calls, "pause "+name+" for "+names[pushed])
+		})
+		h.Screen.BindResumeHandlers(state, func(ctx *gui.Context, popped gui.State) {
+			calls = append(calls, "resume "+name+" after "+names[popped])
+		})
+	}
+
+	err = h.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetText(0, 0, "main", gui.DefaultColor, gui.DefaultColor)
+
+		ctx.PushState(stateDialog)
+		ctx.PushState(stateConfirm)
+	})
+
+	h.AssertGolden(t, "state_stack_pushed")
+
+	var popped gui.State
+	var states []gui.State
+	h.Draw(t, func(ctx *gui.Context) {
+		popped, err = ctx.PopState()
+		states = ctx.States()
+	})
+	if err != nil || popped != stateConfirm {
+		t.Fatalf("PopState() = %v, %v, want %v", popped, err, stateConfirm)
+	}
+	if !slices.Equal(states, []gui.State{gui.NoState, stateDialog}) {
+		t.Fatalf("States() = %v after PopState", states)
+	}
+
+	h.AssertGolden(t, "state_stack_popped")
+
+	// нижнее состояние не снимается
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.PopState()
+		_, err = ctx.PopState()
+	})
+	if !errors.Is(err, gui.ErrLastState) {
+		t.Fatalf("PopState() of the last state returned %v, want ErrLastState", err)
+	}
+
+	want := []string{
+		"pause main for dialog",
+		"enter dialog from main",
+		"pause dialog for confirm",
+		"enter confirm from dialog",
+		"exit confirm to dialog",
+		"resume dialog after confirm",
+		"exit dialog to main",
+		"resume main after dialog",
+	}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("state handlers called as\n%q\nwant\n%q", calls, want)
+	}
+}
+
+func TestGoldenScreenConfig(t *testing.T) {
+	config := gui.ScreenConfig{
+		DefaultCell: gui.Cell{
+			Symbol:     '.',
+			Foreground: gui.Color{R: 128, G: 128, B: 128},
+			Background: gui.Color{B: 128},
+		},
+		DispatchMode: gui.DispatchSerial,
+		OutputMode:   gui.Output16,
+	}
+	h := NewStarted(t, 6, 3, config)
+
+	// пустые и очищенные клетки берутся из DefaultCell, а цвета приводятся к 16 цветам терминала
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetText(0, 0, "config", gui.Color{R: 250, G: 120, B: 10}, gui.DefaultColor)
+		ctx.SetText(1, 1, "xy", gui.DefaultColor, gui.Color{R: 10, G: 200, B: 10})
+		ctx.ClearCell(2, 0)
+	})
+
+	h.AssertGolden(t, "screen_config")
+}
+
+func TestGoldenAttributes(t *testing.T) {
+	config := gui.ScreenConfig{
+		DefaultCell: gui.Cell{
+			Symbol:     ' ',
+			Foreground: gui.DefaultColor,
+			Background: gui.DefaultColor,
+			Attributes: gui.AttrDim,
+		},
+	}
+	h := NewStarted(t, 8, 3, config)
+
+	attributes := []gui.Attribute{
+		gui.AttrBold,
+		gui.AttrItalic,
+		gui.AttrUnderline,
+		gui.AttrReverse,
+		gui.AttrBlink,
+		gui.AttrStrikethrough,
+		gui.NoAttributes,
+	}
+
+	h.Draw(t, func(ctx *gui.Context) {
+		// флаги SetText объединяются
+		ctx.SetText(0, 0, "head", gui.DefaultColor, gui.DefaultColor, gui.AttrBold, gui.AttrUnderline)
+
+		for x, attribute := range attributes {
+			ctx.SetCell(x, 1, gui.Cell{Symbol: 'a', Foreground: gui.DefaultColor, Background: gui.DefaultColor, Attributes: attribute})
+		}
+
+		// очищенная клетка получает начертание DefaultCell
+		ctx.SetText(0, 2, "status", gui.DefaultColor, gui.DefaultColor, gui.AttrReverse)
+		ctx.ClearCell(5, 2)
+	})
+
+	h.AssertGolden(t, "attributes")
+}
+
+func TestGoldenHUD(t *testing.T) {
+	h := NewStarted(t, 8, 4)
+
+	h.Draw(t, func(ctx *gui.Context) {
+		for y := range 8 {
+			ctx.SetText(0, y, fmt.Sprintf("world%d..", y), gui.DefaultColor, gui.DefaultColor)
+		}
+
+		// строка состояния лежит поверх мира, а пустые клетки HUD мир не закрывают
+		ctx.HUD().SetText(0, 3, "status", gui.Color{R: 255}, gui.DefaultColor)
+
+		popup, err := ctx.AddScreenLayer("popup", 1)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		popup.SetText(4, 3, "!!", gui.DefaultColor, gui.Color{B: 255})
+	})
+
+	h.AssertGolden(t, "hud")
+
+	// HUD остаётся на месте, когда видимая область сдвигается
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetViewPosition(1, 3)
+	})
+
+	h.AssertGolden(t, "hud_scrolled")
+}
+
+func TestGoldenViewports(t *testing.T) {
+	h := NewStarted(t, 10, 4)
+
+	h.Draw(t, func(ctx *gui.Context) {
+		for y := range 6 {
+			ctx.SetText(0, y, fmt.Sprintf("%d:abcdefgh", y), gui.DefaultColor, gui.DefaultColor)
+		}
+
+		// второе окно на тот же мир рисуется поверх основного со своей камерой
+		err := ctx.AddViewport("side", gui.NewRect(6, 1, 4, 2), 5, 3)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	h.AssertGolden(t, "viewports")
+
+	// камеры сдвигаются независимо друг от друга
+	var rect gui.Rect
+	var cameraX, cameraY int
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetViewPosition(0, 2)
+
+		err := ctx.SetViewportCamera("side", 0, 0)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		rect, _ = ctx.ViewportRect("side")
+		cameraX, cameraY, _ = ctx.ViewportCamera("side")
+	})
+
+	if rect != gui.NewRect(6, 1, 4, 2) || cameraX != 0 || cameraY != 0 {
+		t.Fatalf("side viewport %v with camera (%d, %d)", rect, cameraX, cameraY)
+	}
+
+	h.AssertGolden(t, "viewports_scrolled")
+
+	h.Draw(t, func(ctx *gui.Context) {
+		err := ctx.RemoveViewport(gui.MainViewport)
+		if !errors.Is(err, gui.ErrMainViewport) {
+			t.Errorf("RemoveViewport(MainViewport) returned %v, want ErrMainViewport", err)
+		}
+
+		err = ctx.RemoveViewport("side")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	h.AssertString(t, "2:abcdefgh\n3:abcdefgh\n4:abcdefgh\n5:abcdefgh")
+}
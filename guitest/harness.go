@@ -0,0 +1,224 @@
+// Package guitest позволяет запускать gui.Screen без терминала и проверять то, что на нём нарисовано.
+package guitest
+
+import (
+	"testing"
+
+	"github.com/gggallahad/gui"
+)
+
+type (
+	Harness struct {
+		Screen  *gui.Screen
+		Backend *gui.MemoryBackend
+
+		runChannel chan struct{}
+	}
+)
+
+// New создаёт Screen поверх MemoryBackend размером width x height и инициализирует его.
+// Обработчики привязываются к h.Screen до вызова Start
+func New(width, height int, screenConfig ...gui.ScreenConfig) (*Harness, error) {
+	var config gui.ScreenConfig
+	if len(screenConfig) != 0 {
+		config = screenConfig[0]
+	}
+
+	backend := gui.NewMemoryBackend(width, height)
+	config.Backend = backend
+
+	// результат теста не должен зависеть от переменных окружения терминала
+	if config.OutputMode == gui.OutputAuto {
+		config.OutputMode = gui.OutputRGB
+	}
+	if config.Graphics == gui.GraphicsAuto {
+		config.Graphics = gui.GraphicsNone
+	}
+
+	screen, err := gui.NewScreen(config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = screen.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	harness := Harness{
+		Screen:     screen,
+		Backend:    backend,
+		runChannel: nil,
+	}
+
+	return &harness, nil
+}
+
+// NewStarted создаёт Harness через New и запускает его. Harness закрывается после теста
+func NewStarted(t testing.TB, width, height int, screenConfig ...gui.ScreenConfig) *Harness {
+	t.Helper()
+
+	h, err := New(width, height, screenConfig...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.Close)
+
+	err = h.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+// Start запускает Screen.Run в отдельной горутине и дожидается выполнения InitHandler'ов
+func (h *Harness) Start() error {
+	if h.runChannel != nil {
+		return nil
+	}
+
+	h.runChannel = make(chan struct{})
+
+	go func() {
+		defer close(h.runChannel)
+
+		h.Screen.Run()
+	}()
+
+	err := h.Screen.Sync()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Draw выполняет task в горутине Run и выводит экран
+func (h *Harness) Draw(t testing.TB, task gui.Task) {
+	t.Helper()
+
+	err := h.Screen.Do(func(ctx *gui.Context) {
+		task(ctx)
+		ctx.Flush()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Inject ставит события в очередь и дожидается их полной обработки
+func (h *Harness) Inject(events ...gui.Event) error {
+	err := h.Start()
+	if err != nil {
+		return err
+	}
+
+	for i := range events {
+		h.Screen.PostEvent(events[i])
+	}
+
+	err = h.Screen.Sync()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *Harness) Symbol(symbols ...rune) error {
+	events := make([]gui.Event, 0, len(symbols))
+	for _, symbol := range symbols {
+		eventKey := &gui.EventKey{
+			Symbol: symbol,
+		}
+		events = append(events, eventKey)
+	}
+
+	err := h.Inject(events...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *Harness) Key(keys ...gui.KeyboardKey) error {
+	events := make([]gui.Event, 0, len(keys))
+	for _, key := range keys {
+		eventKey := &gui.EventKey{
+			Key: key,
+		}
+		events = append(events, eventKey)
+	}
+
+	err := h.Inject(events...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h *Harness) Mouse(x, y int, key gui.MouseKey) error {
+	eventMouse := &gui.EventMouse{
+		X:   x,
+		Y:   y,
+		Key: key,
+	}
+
+	err := h.Inject(eventMouse)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Resize меняет размер MemoryBackend и отправляет EventResize
+func (h *Harness) Resize(width, height int) error {
+	h.Backend.Resize(width, height)
+
+	eventResize := &gui.EventResize{
+		X: width,
+		Y: height,
+	}
+
+	err := h.Inject(eventResize)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Cells возвращает экран на момент последнего Flush, построчно
+func (h *Harness) Cells() []gui.Cell {
+	return h.Backend.Cells()
+}
+
+func (h *Harness) String() string {
+	return h.Backend.String()
+}
+
+// AssertString сравнивает экран на момент последнего Flush с want, строки которого разделены '\n'
+func (h *Harness) AssertString(t testing.TB, want string) {
+	t.Helper()
+
+	got := h.String()
+	if got != want {
+		t.Fatalf("screen %q, want %q", got, want)
+	}
+}
+
+// Close останавливает Screen и ждёт выхода из Run
+func (h *Harness) Close() {
+	if h.runChannel != nil {
+		h.Screen.Do(func(ctx *gui.Context) {
+			ctx.Kill()
+		})
+		<-h.runChannel
+	}
+
+	h.Screen.Close()
+}
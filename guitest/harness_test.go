@@ -0,0 +1,242 @@
+package guitest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gggallahad/gui"
+)
+
+func TestHarnessInject(t *testing.T) {
+	h, err := New(6, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	x := 0
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		switch event := event.(type) {
+		case *gui.EventKey:
+			ctx.SetCell(x, 0, gui.Cell{Symbol: event.Symbol})
+			x++
+		case *gui.EventMouse:
+			ctx.SetCell(event.X, event.Y, gui.Cell{Symbol: '*'})
+		}
+
+		ctx.Flush()
+	})
+
+	// Inject сам запускает Run и возвращается, когда события обработаны
+	err = h.Inject(&gui.EventKey{Symbol: 'a'}, &gui.EventKey{Symbol: 'b'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Symbol('c')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Mouse(5, 1, gui.MouseLeft)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "abc   \n     *"
+	if h.String() != want {
+		t.Fatalf("screen %q, want %q", h.String(), want)
+	}
+
+	cells := h.Cells()
+	if len(cells) != 12 || cells[2].Symbol != 'c' {
+		t.Fatalf("unexpected cells %v", cells)
+	}
+}
+
+func TestHarnessResize(t *testing.T) {
+	h, err := New(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var resized *gui.EventResize
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		eventResize, ok := event.(*gui.EventResize)
+		if !ok {
+			return
+		}
+
+		resized = eventResize
+
+		width, height := ctx.ViewSize()
+		ctx.SetText(0, height-1, "end", gui.DefaultColor, gui.DefaultColor)
+		ctx.SetCell(width-1, 0, gui.Cell{Symbol: '>'})
+		ctx.Flush()
+	})
+
+	err = h.Resize(7, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resized == nil || resized.X != 7 || resized.Y != 3 {
+		t.Fatalf("got resize event %+v, want 7x3", resized)
+	}
+
+	want := "      >\n       \nend    "
+	if h.String() != want {
+		t.Fatalf("screen %q, want %q", h.String(), want)
+	}
+}
+
+func TestHarnessClose(t *testing.T) {
+	h, err := New(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Close()
+
+	err = h.Screen.Do(func(*gui.Context) {})
+	if !errors.Is(err, gui.ErrScreenStopped) {
+		t.Fatalf("Do after Close returned %v, want ErrScreenStopped", err)
+	}
+}
+
+func TestInjectWaitsForEmittedEvents(t *testing.T) {
+	const (
+		rootEvents int = 4
+		depth      int = 8
+	)
+
+	h, err := New(4, 1, gui.ScreenConfig{DispatchMode: gui.DispatchConcurrent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	handled := atomic.Int64{}
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		eventKey, ok := event.(*gui.EventKey)
+		if !ok {
+			return
+		}
+
+		handled.Add(1)
+
+		// каждое событие, пока не достигнута глубина, порождает ещё два
+		if int(eventKey.Symbol-'a') < depth {
+			ctx.Emit(&gui.EventKey{Symbol: eventKey.Symbol + 1})
+			ctx.Emit(&gui.EventKey{Symbol: eventKey.Symbol + 1})
+		}
+	})
+
+	for range rootEvents {
+		err = h.Symbol('a')
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := int64(rootEvents * (1<<(depth+1) - 1))
+	if handled.Load() != want {
+		t.Fatalf("handled %d events before Inject returned, want %d", handled.Load(), want)
+	}
+}
+
+type eventLoaded struct {
+	rows int
+}
+
+func (e *eventLoaded) IsEvent() {}
+
+func TestCustomEventCursorAndOutputMode(t *testing.T) {
+	h, err := New(10, 2, gui.ScreenConfig{OutputMode: gui.Output256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	red := gui.Color{R: 250, G: 10, B: 10}
+
+	var uiContext *gui.Context
+	h.Screen.BindInitHandlers(func(ctx *gui.Context) {
+		uiContext = ctx
+	})
+
+	// собственное событие проходит через middlewares, обработчики состояния и postwares так же, как события терминала
+	calls := []string{}
+	h.Screen.BindGlobalMiddlewares(func(ctx *gui.Context, event gui.Event) {
+		calls = append(calls, "middleware")
+	})
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		eventLoaded, ok := event.(*eventLoaded)
+		if !ok {
+			return
+		}
+
+		calls = append(calls, "handler")
+
+		text := fmt.Sprintf("rows %d", eventLoaded.rows)
+		ctx.SetText(0, 0, text, red, gui.DefaultColor)
+		ctx.SetCursor(len(text), 0)
+		ctx.Flush()
+	})
+	h.Screen.BindGlobalPostwares(func(ctx *gui.Context, event gui.Event) {
+		calls = append(calls, "postware")
+	})
+
+	err = h.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Emit можно вызывать из любой горутины, например из фоновой задачи
+	uiContext.Emit(&eventLoaded{rows: 3})
+
+	err = h.Screen.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"middleware", "handler", "postware"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("custom event passed %q, want %q", calls, want)
+	}
+
+	h.AssertString(t, "rows 3    \n          ")
+
+	x, y := h.Backend.Cursor()
+	if x != 6 || y != 0 {
+		t.Fatalf("cursor at (%d, %d), want (6, 0)", x, y)
+	}
+
+	if h.Screen.OutputMode() != gui.Output256 || h.Backend.OutputMode() != gui.Output256 {
+		t.Fatalf("output mode %v on screen and %v on backend, want Output256", h.Screen.OutputMode(), h.Backend.OutputMode())
+	}
+
+	// цвет выводится таким, каким его покажет терминал с палитрой из 256 цветов
+	cell := h.Backend.Cell(0, 0)
+	if cell.Foreground != red.Quantize(gui.Output256) || cell.Foreground == red {
+		t.Fatalf("foreground %v, want %v", cell.Foreground, red.Quantize(gui.Output256))
+	}
+
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.HideCursor()
+	})
+
+	x, y = h.Backend.Cursor()
+	if x >= 0 || y >= 0 {
+		t.Fatalf("cursor at (%d, %d) after HideCursor", x, y)
+	}
+}
@@ -4,10 +4,36 @@ type (
 	InitHandler       func(*Context)
 	BackgroundHandler func(*Context)
 	Handler           func(*Context, Event)
+	StateHandler      func(*Context, State)
+	Task              func(*Context)
+
+	stateHandlers struct {
+		enter  map[State][]StateHandler
+		exit   map[State][]StateHandler
+		pause  map[State][]StateHandler
+		resume map[State][]StateHandler
+	}
 )
 
 var (
 	emptyInitHandler       InitHandler       = func(*Context) {}
 	emptyBackgroundHandler BackgroundHandler = func(*Context) {}
 	emptyHandler           Handler           = func(*Context, Event) {}
+	emptyStateHandler      StateHandler      = func(*Context, State) {}
 )
+
+func newStateHandlers() *stateHandlers {
+	enter := make(map[State][]StateHandler)
+	exit := make(map[State][]StateHandler)
+	pause := make(map[State][]StateHandler)
+	resume := make(map[State][]StateHandler)
+
+	handlers := stateHandlers{
+		enter:  enter,
+		exit:   exit,
+		pause:  pause,
+		resume: resume,
+	}
+
+	return &handlers
+}
@@ -0,0 +1,303 @@
+package gui
+
+import (
+	"image"
+	"math"
+	"slices"
+)
+
+type (
+	ImageConfig struct {
+		// Mode - сколько точек изображения приходится на клетку: PixelBraille или PixelHalfBlock
+		Mode PixelMode
+		// Dither рассеивает ошибку округления по соседним точкам: в PixelHalfBlock - ошибку цвета
+		// под режим цвета экрана, в PixelBraille - ошибку яркости, когда точка гасится или зажигается
+		Dither bool
+		// Stretch растягивает изображение на весь прямоугольник, не сохраняя пропорции
+		Stretch bool
+		// Background - фон клеток изображения, с ним смешиваются полупрозрачные точки
+		Background Color
+	}
+
+	// imageDot - точка изображения, уменьшенного до сетки точек клеток, каналы в диапазоне 0-255
+	imageDot struct {
+		r       float64
+		g       float64
+		b       float64
+		visible bool
+	}
+)
+
+// DrawImage рисует img в прямоугольнике клеток rect и возвращает прямоугольник, который оно заняло.
+// Без Stretch изображение вписывается в rect с сохранением пропорций и выравнивается по центру,
+// клетка считается вдвое выше своей ширины
+func (ctx *Context) DrawImage(rect Rect, img image.Image, config ImageConfig) Rect {
+	imageBounds := img.Bounds()
+	if rect.Empty() || imageBounds.Empty() {
+		return Rect{}
+	}
+
+	cellWidth, cellHeight := config.Mode.cellSize()
+	dotsWidth, dotsHeight := imageDotsSize(rect, imageBounds, cellWidth, cellHeight, config.Stretch)
+
+	dots := sampleImage(img, dotsWidth, dotsHeight, config.Background)
+
+	pixels := NewPixelCanvas(dotsWidth, dotsHeight, config.Mode)
+	if config.Mode == PixelHalfBlock {
+		fillHalfBlockPixels(pixels, dots, config.Dither, ctx.renderer.outputMode)
+	} else {
+		fillBraillePixels(pixels, dots, config.Dither)
+	}
+
+	usedWidth, usedHeight := pixels.CellSize()
+	usedRect := NewRect(rect.X+(rect.Width-usedWidth)/2, rect.Y+(rect.Height-usedHeight)/2, usedWidth, usedHeight)
+
+	// клетки без точек тоже принадлежат изображению, поэтому сначала прямоугольник заливается фоном
+	backgroundCell := Cell{
+		Symbol:     DefaultSymbol,
+		Foreground: DefaultColor,
+		Background: config.Background,
+	}
+	ctx.FillRect(usedRect, backgroundCell)
+
+	ctx.DrawPixels(usedRect.X, usedRect.Y, pixels, config.Background)
+
+	return usedRect
+}
+
+// imageDotsSize возвращает размер изображения в точках клеток. Точки обоих режимов квадратные,
+// поэтому пропорции сохраняются одним масштабом по обеим осям
+func imageDotsSize(rect Rect, imageBounds image.Rectangle, cellWidth, cellHeight int, stretch bool) (int, int) {
+	maxWidth := rect.Width * cellWidth
+	maxHeight := rect.Height * cellHeight
+
+	if stretch {
+		return maxWidth, maxHeight
+	}
+
+	imageWidth := float64(imageBounds.Dx())
+	imageHeight := float64(imageBounds.Dy())
+
+	scale := math.Min(float64(maxWidth)/imageWidth, float64(maxHeight)/imageHeight)
+
+	width := min(max(int(math.Round(imageWidth*scale)), 1), maxWidth)
+	height := min(max(int(math.Round(imageHeight*scale)), 1), maxHeight)
+
+	return width, height
+}
+
+// sampleImage уменьшает img до width×height точек усреднением, а увеличивает повторением пикселей.
+// Полупрозрачные пиксели смешиваются с background, а при DefaultColor становятся прозрачными
+func sampleImage(img image.Image, width, height int, background Color) []imageDot {
+	imageBounds := img.Bounds()
+	imageWidth := imageBounds.Dx()
+	imageHeight := imageBounds.Dy()
+
+	dots := make([]imageDot, width*height)
+
+	for y := range height {
+		startY, endY := sampleRange(y, height, imageHeight)
+
+		for x := range width {
+			startX, endX := sampleRange(x, width, imageWidth)
+
+			var r, g, b, a float64
+			for imageY := startY; imageY < endY; imageY++ {
+				for imageX := startX; imageX < endX; imageX++ {
+					// At возвращает каналы, уже умноженные на прозрачность
+					pixelR, pixelG, pixelB, pixelA := img.At(imageBounds.Min.X+imageX, imageBounds.Min.Y+imageY).RGBA()
+					r += float64(pixelR)
+					g += float64(pixelG)
+					b += float64(pixelB)
+					a += float64(pixelA)
+				}
+			}
+
+			count := float64((endX - startX) * (endY - startY))
+			dots[y*width+x] = newImageDot(r/count, g/count, b/count, a/count, background)
+		}
+	}
+
+	return dots
+}
+
+// sampleRange возвращает пиксели исходной оси, которые попадают в точку index из count
+func sampleRange(index, count, imageSize int) (int, int) {
+	start := index * imageSize / count
+	end := (index + 1) * imageSize / count
+
+	if end <= start {
+		end = start + 1
+	}
+
+	return start, min(end, imageSize)
+}
+
+// newImageDot переводит усреднённые 16-битные каналы с умноженной прозрачностью в точку
+func newImageDot(r, g, b, a float64, background Color) imageDot {
+	const channelMax float64 = 0xffff
+
+	if background == DefaultColor {
+		if a < channelMax/2 {
+			return imageDot{}
+		}
+
+		dot := imageDot{
+			r:       r / a * 255,
+			g:       g / a * 255,
+			b:       b / a * 255,
+			visible: true,
+		}
+
+		return dot
+	}
+
+	transparency := 1 - a/channelMax
+
+	dot := imageDot{
+		r:       r/channelMax*255 + float64(background.R)*transparency,
+		g:       g/channelMax*255 + float64(background.G)*transparency,
+		b:       b/channelMax*255 + float64(background.B)*transparency,
+		visible: true,
+	}
+
+	return dot
+}
+
+func fillHalfBlockPixels(pixels *PixelCanvas, dots []imageDot, dither bool, outputMode OutputMode) {
+	width, height := pixels.Size()
+
+	for y := range height {
+		for x := range width {
+			dot := dots[y*width+x]
+			if !dot.visible {
+				continue
+			}
+
+			color := dot.color()
+			if dither {
+				color = color.Quantize(outputMode)
+
+				diffuseError(dots, width, height, x, y, dot.r-float64(color.R), dot.g-float64(color.G), dot.b-float64(color.B))
+			}
+
+			pixels.SetPixel(x, y, color)
+		}
+	}
+}
+
+// fillBraillePixels зажигает точки ярче середины. У клетки Брайля один цвет,
+// поэтому все её точки получают средний цвет зажжённых точек
+func fillBraillePixels(pixels *PixelCanvas, dots []imageDot, dither bool) {
+	width, height := pixels.Size()
+
+	// ошибка яркости рассеивается по копии, а цвет клеток берётся из исходных точек
+	ditheredDots := slices.Clone(dots)
+
+	lit := make([]bool, len(dots))
+	for y := range height {
+		for x := range width {
+			index := y*width + x
+
+			dot := ditheredDots[index]
+			if !dot.visible {
+				continue
+			}
+
+			luminance := dot.luminance()
+			lit[index] = luminance >= 128
+
+			if dither {
+				luminanceError := luminance
+				if lit[index] {
+					luminanceError -= 255
+				}
+
+				diffuseError(ditheredDots, width, height, x, y, luminanceError, luminanceError, luminanceError)
+			}
+		}
+	}
+
+	cellsWidth, cellsHeight := pixels.CellSize()
+	for cellY := range cellsHeight {
+		for cellX := range cellsWidth {
+			var r, g, b, count float64
+			for y := cellY * 4; y < min(cellY*4+4, height); y++ {
+				for x := cellX * 2; x < min(cellX*2+2, width); x++ {
+					index := y*width + x
+					if !lit[index] {
+						continue
+					}
+
+					r += dots[index].r
+					g += dots[index].g
+					b += dots[index].b
+					count++
+				}
+			}
+
+			if count == 0 {
+				continue
+			}
+
+			average := imageDot{r: r / count, g: g / count, b: b / count}.color()
+
+			for y := cellY * 4; y < min(cellY*4+4, height); y++ {
+				for x := cellX * 2; x < min(cellX*2+2, width); x++ {
+					if lit[y*width+x] {
+						pixels.SetPixel(x, y, average)
+					}
+				}
+			}
+		}
+	}
+}
+
+// diffuseError раскладывает ошибку точки (x, y) на ещё не обработанных соседей по Флойду-Стейнбергу
+func diffuseError(dots []imageDot, width, height, x, y int, errR, errG, errB float64) {
+	neighbours := [4]struct {
+		dx     int
+		dy     int
+		weight float64
+	}{
+		{1, 0, 7.0 / 16},
+		{-1, 1, 3.0 / 16},
+		{0, 1, 5.0 / 16},
+		{1, 1, 1.0 / 16},
+	}
+
+	for _, neighbour := range neighbours {
+		neighbourX := x + neighbour.dx
+		neighbourY := y + neighbour.dy
+		if neighbourX < 0 || neighbourX >= width || neighbourY >= height {
+			continue
+		}
+
+		dot := &dots[neighbourY*width+neighbourX]
+		if !dot.visible {
+			continue
+		}
+
+		dot.r += errR * neighbour.weight
+		dot.g += errG * neighbour.weight
+		dot.b += errB * neighbour.weight
+	}
+}
+
+func (d imageDot) color() Color {
+	color := Color{
+		R: clampChannel(d.r),
+		G: clampChannel(d.g),
+		B: clampChannel(d.b),
+	}
+
+	return color
+}
+
+func (d imageDot) luminance() float64 {
+	return 0.2126*d.r + 0.7152*d.g + 0.0722*d.b
+}
+
+func clampChannel(value float64) int {
+	return int(math.Round(min(max(value, 0), 255)))
+}
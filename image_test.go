@@ -0,0 +1,219 @@
+package gui
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestImageDotsSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		rect       Rect
+		image      image.Rectangle
+		mode       PixelMode
+		stretch    bool
+		wantWidth  int
+		wantHeight int
+	}{
+		{
+			name:       "wide braille",
+			rect:       NewRect(0, 0, 10, 10),
+			image:      image.Rect(0, 0, 40, 20),
+			mode:       PixelBraille,
+			wantWidth:  20,
+			wantHeight: 10,
+		},
+		{
+			name:       "tall braille",
+			rect:       NewRect(0, 0, 10, 5),
+			image:      image.Rect(0, 0, 10, 40),
+			mode:       PixelBraille,
+			wantWidth:  5,
+			wantHeight: 20,
+		},
+		{
+			name:       "half block",
+			rect:       NewRect(0, 0, 10, 10),
+			image:      image.Rect(0, 0, 100, 50),
+			mode:       PixelHalfBlock,
+			wantWidth:  10,
+			wantHeight: 5,
+		},
+		{
+			name:       "upscale",
+			rect:       NewRect(0, 0, 4, 4),
+			image:      image.Rect(5, 5, 7, 7),
+			mode:       PixelHalfBlock,
+			wantWidth:  4,
+			wantHeight: 4,
+		},
+		{
+			name:       "thin line keeps a dot",
+			rect:       NewRect(0, 0, 2, 2),
+			image:      image.Rect(0, 0, 1000, 1),
+			mode:       PixelBraille,
+			wantWidth:  4,
+			wantHeight: 1,
+		},
+		{
+			name:       "stretch",
+			rect:       NewRect(0, 0, 3, 2),
+			image:      image.Rect(0, 0, 100, 1),
+			mode:       PixelBraille,
+			stretch:    true,
+			wantWidth:  6,
+			wantHeight: 8,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cellWidth, cellHeight := test.mode.cellSize()
+
+			width, height := imageDotsSize(test.rect, test.image, cellWidth, cellHeight, test.stretch)
+			if width != test.wantWidth || height != test.wantHeight {
+				t.Fatalf("imageDotsSize() = %d×%d, want %d×%d", width, height, test.wantWidth, test.wantHeight)
+			}
+		})
+	}
+}
+
+func TestSampleImage(t *testing.T) {
+	blue := Color{B: 255}
+
+	// прозрачный, полупрозрачный красный, красный с прозрачностью меньше половины и непрозрачный белый
+	pixels := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	pixels.SetRGBA(1, 0, color.RGBA{R: 128, A: 128})
+	pixels.SetRGBA(2, 0, color.RGBA{R: 100, A: 100})
+	pixels.SetRGBA(3, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	tests := []struct {
+		name       string
+		background Color
+		want       []imageDot
+	}{
+		{
+			name:       "default background",
+			background: DefaultColor,
+			want: []imageDot{
+				{},
+				{r: 255, visible: true},
+				{},
+				{r: 255, g: 255, b: 255, visible: true},
+			},
+		},
+		{
+			name:       "blue background",
+			background: blue,
+			want: []imageDot{
+				{b: 255, visible: true},
+				{r: 128, b: 127, visible: true},
+				{r: 100, b: 155, visible: true},
+				{r: 255, g: 255, b: 255, visible: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dots := sampleImage(pixels, 4, 1, test.background)
+
+			for i, dot := range dots {
+				if dot.visible != test.want[i].visible || dot.color() != test.want[i].color() {
+					t.Errorf("dot %d = %v %v, want %v %v", i, dot.visible, dot.color(), test.want[i].visible, test.want[i].color())
+				}
+			}
+		})
+	}
+}
+
+func TestSampleImageScale(t *testing.T) {
+	// чёрный и белый пиксель усредняются в серый, а при увеличении каждый повторяется
+	pixels := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	pixels.SetRGBA(0, 0, color.RGBA{A: 255})
+	pixels.SetRGBA(1, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	dots := sampleImage(pixels, 1, 1, DefaultColor)
+	want := Color{R: 128, G: 128, B: 128}
+	if dots[0].color() != want {
+		t.Fatalf("downscaled dot = %v, want %v", dots[0].color(), want)
+	}
+
+	dots = sampleImage(pixels, 4, 2, DefaultColor)
+	black := Color{}
+	white := Color{R: 255, G: 255, B: 255}
+	wantDots := []Color{black, black, white, white, black, black, white, white}
+	for i, dot := range dots {
+		if dot.color() != wantDots[i] {
+			t.Fatalf("upscaled dot %d = %v, want %v", i, dot.color(), wantDots[i])
+		}
+	}
+}
+
+func TestFillBraillePixels(t *testing.T) {
+	// серый темнее середины: без рассеивания точки не зажигаются, с рассеиванием зажигается примерно их доля яркости
+	grey := imageDot{r: 100, g: 100, b: 100, visible: true}
+
+	tests := []struct {
+		name    string
+		dither  bool
+		wantMin int
+		wantMax int
+	}{
+		{name: "threshold", dither: false, wantMin: 0, wantMax: 0},
+		{name: "dither", dither: true, wantMin: 20, wantMax: 30},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dots := make([]imageDot, 8*8)
+			for i := range dots {
+				dots[i] = grey
+			}
+
+			pixels := NewPixelCanvas(8, 8, PixelBraille)
+			fillBraillePixels(pixels, dots, test.dither)
+
+			lit := 0
+			for y := range 8 {
+				for x := range 8 {
+					color, ok := pixels.Pixel(x, y)
+					if !ok {
+						continue
+					}
+
+					lit++
+
+					// рассеивается только яркость, цвет точек остаётся исходным
+					if color != grey.color() {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, color, grey.color())
+					}
+				}
+			}
+
+			if lit < test.wantMin || lit > test.wantMax {
+				t.Fatalf("%d of 64 dots lit, want %d-%d", lit, test.wantMin, test.wantMax)
+			}
+		})
+	}
+}
+
+func TestFillBraillePixelsCellColor(t *testing.T) {
+	// у клетки Брайля один цвет: зажжённые точки получают их средний цвет, а тёмные и прозрачные не зажигаются
+	dots := make([]imageDot, 2*4)
+	dots[0] = imageDot{r: 255, g: 255, b: 255, visible: true}
+	dots[1] = imageDot{r: 255, g: 255, b: 55, visible: true}
+	dots[2] = imageDot{r: 10, g: 10, b: 10, visible: true}
+
+	pixels := NewPixelCanvas(2, 4, PixelBraille)
+	fillBraillePixels(pixels, dots, false)
+
+	want := Color{R: 255, G: 255, B: 155}
+	for index, wantLit := range []bool{true, true, false, false, false, false, false, false} {
+		color, ok := pixels.Pixel(index%2, index/2)
+		if ok != wantLit || (ok && color != want) {
+			t.Fatalf("pixel %d = %v, %v, want %v, %v", index, color, ok, want, wantLit)
+		}
+	}
+}
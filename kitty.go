@@ -0,0 +1,82 @@
+package gui
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"strconv"
+)
+
+const (
+	// размер части base64 в одной команде: протокол Kitty не принимает больше 4096 байт за раз
+	kittyChunkSize int = 4096
+)
+
+// EncodeKitty кодирует img командой протокола Kitty, которая передаёт картинку в терминал под номером id,
+// но не показывает её, см. kittyPlace. Точки передаются в RGBA, сжатыми zlib, ответы терминала отключены
+func EncodeKitty(img image.Image, id ImageID) []byte {
+	bounds := img.Bounds()
+
+	pixels := make([]byte, 0, bounds.Dx()*bounds.Dy()*4)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			pixels = append(pixels, pixel.R, pixel.G, pixel.B, pixel.A)
+		}
+	}
+
+	// запись в bytes.Buffer не возвращает ошибок
+	compressed := bytes.Buffer{}
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(pixels)
+	writer.Close()
+
+	payload := base64.StdEncoding.EncodeToString(compressed.Bytes())
+
+	buffer := bytes.Buffer{}
+	for start := 0; start == 0 || start < len(payload); start += kittyChunkSize {
+		end := min(start+kittyChunkSize, len(payload))
+
+		more := 0
+		if end < len(payload) {
+			more = 1
+		}
+
+		buffer.WriteString("\x1b_G")
+		if start == 0 {
+			buffer.WriteString("a=t,f=32,o=z,s=")
+			buffer.WriteString(strconv.Itoa(bounds.Dx()))
+			buffer.WriteString(",v=")
+			buffer.WriteString(strconv.Itoa(bounds.Dy()))
+			buffer.WriteString(",i=")
+			buffer.WriteString(strconv.FormatUint(uint64(id), 10))
+			buffer.WriteString(",q=2,")
+		}
+		buffer.WriteString("m=")
+		buffer.WriteString(strconv.Itoa(more))
+		buffer.WriteByte(';')
+		buffer.WriteString(payload[start:end])
+		buffer.WriteString("\x1b\\")
+	}
+
+	return buffer.Bytes()
+}
+
+// kittyPlace показывает переданную картинку id в позиции курсора на columns×rows клетках, не сдвигая курсор.
+// Номер размещения постоянный, поэтому повторная команда переносит картинку, а не показывает вторую
+func kittyPlace(id ImageID, columns, rows int) []byte {
+	return []byte("\x1b_Ga=p,i=" + strconv.FormatUint(uint64(id), 10) + ",p=1,c=" + strconv.Itoa(columns) +
+		",r=" + strconv.Itoa(rows) + ",C=1,q=2\x1b\\")
+}
+
+// kittyHide убирает картинку id с экрана, но оставляет её данные в терминале для следующего kittyPlace
+func kittyHide(id ImageID) []byte {
+	return []byte("\x1b_Ga=d,d=i,i=" + strconv.FormatUint(uint64(id), 10) + ",q=2\x1b\\")
+}
+
+// kittyDelete удаляет картинку id вместе с её данными в терминале
+func kittyDelete(id ImageID) []byte {
+	return []byte("\x1b_Ga=d,d=I,i=" + strconv.FormatUint(uint64(id), 10) + ",q=2\x1b\\")
+}
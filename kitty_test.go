@@ -0,0 +1,147 @@
+package gui
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestEncodeKitty(t *testing.T) {
+	red := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	red.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+
+	// шум почти не сжимается, поэтому данные не помещаются в одну часть
+	random := rand.New(rand.NewPCG(1, 2))
+	noise := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for i := range noise.Pix {
+		noise.Pix[i] = byte(random.IntN(256))
+	}
+
+	tests := []struct {
+		name   string
+		img    *image.NRGBA
+		id     ImageID
+		header string
+		chunks int
+	}{
+		{
+			name:   "transparent dot",
+			img:    image.NewNRGBA(image.Rect(0, 0, 1, 1)),
+			id:     3,
+			header: "\x1b_Ga=t,f=32,o=z,s=1,v=1,i=3,q=2,m=0;",
+			chunks: 1,
+		},
+		{
+			name:   "red dot",
+			img:    red,
+			id:     7,
+			header: "\x1b_Ga=t,f=32,o=z,s=1,v=1,i=7,q=2,m=0;",
+			chunks: 1,
+		},
+		{
+			name:   "several chunks",
+			img:    noise,
+			id:     1,
+			header: "\x1b_Ga=t,f=32,o=z,s=64,v=64,i=1,q=2,m=1;",
+			chunks: 6,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded := string(EncodeKitty(test.img, test.id))
+
+			if !strings.HasPrefix(encoded, test.header) {
+				t.Fatalf("EncodeKitty starts with %q, want %q", encoded[:min(len(encoded), 64)], test.header)
+			}
+
+			commands := strings.SplitAfter(encoded, "\x1b\\")
+			if commands[len(commands)-1] != "" {
+				t.Fatalf("EncodeKitty does not end with ST: %q", commands[len(commands)-1])
+			}
+			commands = commands[:len(commands)-1]
+
+			if len(commands) != test.chunks {
+				t.Fatalf("got %d chunks, want %d", len(commands), test.chunks)
+			}
+
+			payload := strings.Builder{}
+			for i, command := range commands {
+				prefix := "\x1b_Gm=1;"
+				switch {
+				case i == 0:
+					prefix = test.header
+				case i == len(commands)-1:
+					prefix = "\x1b_Gm=0;"
+				}
+
+				if !strings.HasPrefix(command, prefix) {
+					t.Fatalf("chunk %d starts with %q, want %q", i, command[:min(len(command), 64)], prefix)
+				}
+
+				chunk := strings.TrimSuffix(strings.TrimPrefix(command, prefix), "\x1b\\")
+				if len(chunk) > kittyChunkSize {
+					t.Fatalf("chunk %d has %d bytes of base64", i, len(chunk))
+				}
+				payload.WriteString(chunk)
+			}
+
+			compressed, err := base64.StdEncoding.DecodeString(payload.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := zlib.NewReader(bytes.NewReader(compressed))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pixels, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(pixels, test.img.Pix) {
+				t.Fatalf("decoded %d bytes of RGBA that differ from the image", len(pixels))
+			}
+		})
+	}
+}
+
+func TestKittyCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{
+			name: "place",
+			got:  kittyPlace(5, 2, 3),
+			want: "\x1b_Ga=p,i=5,p=1,c=2,r=3,C=1,q=2\x1b\\",
+		},
+		{
+			name: "hide",
+			got:  kittyHide(5),
+			want: "\x1b_Ga=d,d=i,i=5,q=2\x1b\\",
+		},
+		{
+			name: "delete",
+			got:  kittyDelete(42),
+			want: "\x1b_Ga=d,d=I,i=42,q=2\x1b\\",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if string(test.got) != test.want {
+				t.Fatalf("got %q, want %q", test.got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package gui
+
+import (
+	"slices"
+)
+
+const (
+	// BaseLayer - слой, в который рисует Context, если слой не выбран через Layer
+	BaseLayer string = "base"
+	// HUDLayer - слой в экранных координатах, который возвращает HUD
+	HUDLayer string = "hud"
+)
+
+type (
+	layer struct {
+		name    string
+		zIndex  int
+		visible bool
+		// клетки, равные transparent, как и незаписанные клетки, не перекрывают нижние слои
+		transparent Cell
+		canvas      *canvas
+		// стороны символов рамки, нарисованных DrawHLine, DrawVLine и DrawBox
+		boxCells map[boxCellKey]boxCell
+		// слой в экранных координатах не сдвигается вместе с видимой областью и лежит поверх всех слоёв мира
+		screenSpace bool
+	}
+
+	// слои упорядочены по zIndex, при равных zIndex - по порядку добавления
+	layers struct {
+		list []*layer
+	}
+)
+
+var (
+	TransparentCell Cell = Cell{}
+)
+
+func newLayer(name string, zIndex int, screenSpace bool) *layer {
+	canvas := newCanvas()
+	boxCells := make(map[boxCellKey]boxCell)
+
+	l := layer{
+		name:        name,
+		zIndex:      zIndex,
+		visible:     true,
+		transparent: TransparentCell,
+		canvas:      canvas,
+		boxCells:    boxCells,
+		screenSpace: screenSpace,
+	}
+
+	return &l
+}
+
+func newLayers() *layers {
+	baseLayer := newLayer(BaseLayer, 0, false)
+	hudLayer := newLayer(HUDLayer, 0, true)
+
+	l := layers{
+		list: []*layer{baseLayer, hudLayer},
+	}
+
+	return &l
+}
+
+func (l *layers) find(name string) (*layer, bool) {
+	for i := range l.list {
+		if l.list[i].name == name {
+			return l.list[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func (l *layers) add(newLayer *layer) {
+	l.list = append(l.list, newLayer)
+	l.sort()
+}
+
+func (l *layers) remove(name string) {
+	l.list = slices.DeleteFunc(l.list, func(current *layer) bool {
+		return current.name == name
+	})
+}
+
+func (l *layers) sort() {
+	slices.SortStableFunc(l.list, func(a, b *layer) int {
+		return a.zIndex - b.zIndex
+	})
+}
+
+// composeWorld возвращает клетку мира (x, y), видимую сквозь все видимые слои мира
+func (l *layers) composeWorld(x, y int, defaultCell Cell) Cell {
+	cell := defaultCell
+	for _, current := range l.list {
+		if current.screenSpace {
+			continue
+		}
+
+		cell = current.over(x, y, cell)
+	}
+
+	return cell
+}
+
+// composeScreen накладывает на cell видимые экранные слои в клетке экрана (x, y)
+func (l *layers) composeScreen(x, y int, cell Cell) Cell {
+	for _, current := range l.list {
+		if !current.screenSpace {
+			continue
+		}
+
+		cell = current.over(x, y, cell)
+	}
+
+	return cell
+}
+
+// over возвращает клетку слоя, если она непрозрачна, иначе below
+func (l *layer) over(x, y int, below Cell) Cell {
+	if !l.visible {
+		return below
+	}
+
+	cell, ok := l.canvas.get(x, y)
+	if !ok || cell == l.transparent {
+		return below
+	}
+
+	return cell
+}
+
+// пустая клетка базового слоя - клетка по умолчанию, остальных слоёв - прозрачная
+func (l *layer) emptyCell(defaultCell Cell) Cell {
+	if l.name == BaseLayer {
+		return defaultCell
+	}
+
+	return l.transparent
+}
+
+// layers
+
+// AddLayer создаёт слой мира над или под остальными в зависимости от zIndex. Базовый слой имеет zIndex 0
+func (ctx *Context) AddLayer(name string, zIndex int) (*Context, error) {
+	layerContext, err := ctx.addLayer(name, zIndex, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return layerContext, nil
+}
+
+// AddScreenLayer создаёт слой в экранных координатах. Такие слои лежат поверх мира
+// и упорядочены по zIndex между собой. Слой HUDLayer имеет zIndex 0
+func (ctx *Context) AddScreenLayer(name string, zIndex int) (*Context, error) {
+	layerContext, err := ctx.addLayer(name, zIndex, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return layerContext, nil
+}
+
+// HUD возвращает Context, который рисует в экранных координатах: нарисованное остаётся на месте при SetViewPosition
+func (ctx *Context) HUD() *Context {
+	hudLayer, _ := ctx.layers.find(HUDLayer)
+
+	hudContext := ctx.withLayer(hudLayer)
+
+	return hudContext
+}
+
+func (ctx *Context) addLayer(name string, zIndex int, screenSpace bool) (*Context, error) {
+	_, ok := ctx.layers.find(name)
+	if ok {
+		return nil, ErrLayerExists
+	}
+
+	addedLayer := newLayer(name, zIndex, screenSpace)
+	ctx.layers.add(addedLayer)
+
+	layerContext := ctx.withLayer(addedLayer)
+
+	return layerContext, nil
+}
+
+// Layer возвращает Context, который рисует в слой name
+func (ctx *Context) Layer(name string) (*Context, error) {
+	foundLayer, ok := ctx.layers.find(name)
+	if !ok {
+		return nil, ErrLayerNotFound
+	}
+
+	layerContext := ctx.withLayer(foundLayer)
+
+	return layerContext, nil
+}
+
+func (ctx *Context) RemoveLayer(name string) error {
+	if name == BaseLayer || name == HUDLayer {
+		return ErrBuiltinLayer
+	}
+
+	foundLayer, ok := ctx.layers.find(name)
+	if !ok {
+		return ErrLayerNotFound
+	}
+
+	// картинки слоя стираются вместе с ним
+	ctx.graphics.removeLayer(foundLayer)
+
+	ctx.layers.remove(name)
+
+	return nil
+}
+
+func (ctx *Context) SetLayerVisible(name string, visible bool) error {
+	foundLayer, ok := ctx.layers.find(name)
+	if !ok {
+		return ErrLayerNotFound
+	}
+
+	foundLayer.visible = visible
+
+	return nil
+}
+
+func (ctx *Context) SetLayerZIndex(name string, zIndex int) error {
+	foundLayer, ok := ctx.layers.find(name)
+	if !ok {
+		return ErrLayerNotFound
+	}
+
+	foundLayer.zIndex = zIndex
+	ctx.layers.sort()
+
+	return nil
+}
+
+// SetLayerTransparent задаёт клетку, которая в слое name считается прозрачной (по умолчанию TransparentCell)
+func (ctx *Context) SetLayerTransparent(name string, transparent Cell) error {
+	foundLayer, ok := ctx.layers.find(name)
+	if !ok {
+		return ErrLayerNotFound
+	}
+
+	foundLayer.transparent = transparent
+
+	return nil
+}
+
+// Layers возвращает имена слоёв снизу вверх
+func (ctx *Context) Layers() []string {
+	names := make([]string, 0, len(ctx.layers.list))
+	for i := range ctx.layers.list {
+		names = append(names, ctx.layers.list[i].name)
+	}
+
+	return names
+}
+
+// LayerName возвращает имя слоя, в который рисует ctx
+func (ctx *Context) LayerName() string {
+	return ctx.layer.name
+}
+
+func (ctx *Context) withLayer(targetLayer *layer) *Context {
+	layerContext := *ctx
+	layerContext.layer = targetLayer
+
+	return &layerContext
+}
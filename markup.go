@@ -0,0 +1,172 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// StyledRun - кусок текста с одним начертанием
+	StyledRun struct {
+		Text       string
+		Foreground Color
+		Background Color
+		Attributes Attribute
+	}
+)
+
+var (
+	attributeTags map[string]Attribute = map[string]Attribute{
+		"bold":          AttrBold,
+		"italic":        AttrItalic,
+		"underline":     AttrUnderline,
+		"reverse":       AttrReverse,
+		"blink":         AttrBlink,
+		"strikethrough": AttrStrikethrough,
+		"dim":           AttrDim,
+	}
+)
+
+// ParseMarkup разбирает строку с разметкой вида "[fg=#01e5d2,bold]cursorX:[/] 5".
+// Тег [...] накладывает на текущее начертание fg=, bg= (#rrggbb, #rgb или default) и флаги атрибутов,
+// [/] возвращает начертание, действовавшее до последнего тега. "[[" выводит литеральную '['.
+// Начертание вне тегов задаётся base
+func ParseMarkup(markup string, base StyledRun) ([]StyledRun, error) {
+	runs := make([]StyledRun, 0)
+	styles := []StyledRun{base}
+
+	text := strings.Builder{}
+	flushText := func() {
+		if text.Len() == 0 {
+			return
+		}
+
+		run := styles[len(styles)-1]
+		run.Text = text.String()
+		runs = append(runs, run)
+
+		text.Reset()
+	}
+
+	for i := 0; i < len(markup); i++ {
+		if markup[i] != '[' {
+			text.WriteByte(markup[i])
+			continue
+		}
+
+		if i+1 < len(markup) && markup[i+1] == '[' {
+			text.WriteByte('[')
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(markup[i:], ']')
+		if end == -1 {
+			return nil, fmt.Errorf("%w: unterminated tag at offset %d", ErrInvalidMarkup, i)
+		}
+
+		tag := markup[i+1 : i+end]
+		i += end
+
+		flushText()
+
+		if tag == "/" {
+			if len(styles) == 1 {
+				return nil, fmt.Errorf("%w: unmatched [/] at offset %d", ErrInvalidMarkup, i-end)
+			}
+
+			styles = styles[:len(styles)-1]
+			continue
+		}
+
+		style, err := applyTag(styles[len(styles)-1], tag)
+		if err != nil {
+			return nil, err
+		}
+
+		styles = append(styles, style)
+	}
+
+	flushText()
+
+	return runs, nil
+}
+
+// MarkupWidth возвращает ширину текста разметки в клетках
+func MarkupWidth(markup string) (int, error) {
+	runs, err := ParseMarkup(markup, StyledRun{})
+	if err != nil {
+		return 0, err
+	}
+
+	width := 0
+	for i := range runs {
+		width += TextWidth(runs[i].Text)
+	}
+
+	return width, nil
+}
+
+func applyTag(style StyledRun, tag string) (StyledRun, error) {
+	for _, item := range strings.Split(tag, ",") {
+		item = strings.TrimSpace(item)
+
+		key, value, isPair := strings.Cut(item, "=")
+		if !isPair {
+			attribute, ok := attributeTags[item]
+			if !ok {
+				return style, fmt.Errorf("%w: unknown attribute %q", ErrInvalidMarkup, item)
+			}
+
+			style.Attributes |= attribute
+			continue
+		}
+
+		color, err := ParseColor(value)
+		if err != nil {
+			return style, fmt.Errorf("%w: invalid color %q", ErrInvalidMarkup, value)
+		}
+
+		switch key {
+		case "fg":
+			style.Foreground = color
+		case "bg":
+			style.Background = color
+		default:
+			return style, fmt.Errorf("%w: unknown key %q", ErrInvalidMarkup, key)
+		}
+	}
+
+	return style, nil
+}
+
+// draw
+
+// SetMarkup рисует разметку (см. ParseMarkup) одной строкой и возвращает её ширину в клетках.
+// foreground, background и attributes задают начертание вне тегов
+func (ctx *Context) SetMarkup(x, y int, markup string, foreground, background Color, attributes ...Attribute) (int, error) {
+	base := StyledRun{
+		Foreground: foreground,
+		Background: background,
+		Attributes: joinAttributes(attributes),
+	}
+
+	runs, err := ParseMarkup(markup, base)
+	if err != nil {
+		return 0, err
+	}
+
+	width := ctx.SetStyledRuns(x, y, runs)
+
+	return width, nil
+}
+
+// SetStyledRuns рисует куски подряд одной строкой и возвращает общую ширину в клетках
+func (ctx *Context) SetStyledRuns(x, y int, runs []StyledRun) int {
+	width := 0
+	for i := range runs {
+		width += ctx.SetText(x+width, y, runs[i].Text, runs[i].Foreground, runs[i].Background, runs[i].Attributes)
+	}
+
+	return width
+}
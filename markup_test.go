@@ -0,0 +1,108 @@
+package gui
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	base := StyledRun{
+		Foreground: DefaultColor,
+		Background: PaletteColor(4),
+	}
+
+	styled := func(text string, foreground, background Color, attributes Attribute) StyledRun {
+		return StyledRun{Text: text, Foreground: foreground, Background: background, Attributes: attributes}
+	}
+
+	red := Color{R: 255}
+	teal := Color{R: 0x01, G: 0xe5, B: 0xd2}
+
+	tests := []struct {
+		name string
+		text string
+		want []StyledRun
+	}{
+		{
+			name: "plain",
+			text: "plain ]text",
+			want: []StyledRun{styled("plain ]text", DefaultColor, PaletteColor(4), NoAttributes)},
+		},
+		{
+			name: "escaped bracket",
+			text: "[[x] [[[bold]y[/]",
+			want: []StyledRun{
+				styled("[x] [", DefaultColor, PaletteColor(4), NoAttributes),
+				styled("y", DefaultColor, PaletteColor(4), AttrBold),
+			},
+		},
+		{
+			name: "colors",
+			text: "[fg=#01e5d2,bg=#f00]a[/]b[fg=default]c",
+			want: []StyledRun{
+				styled("a", teal, red, NoAttributes),
+				styled("b", DefaultColor, PaletteColor(4), NoAttributes),
+				styled("c", DefaultColor, PaletteColor(4), NoAttributes),
+			},
+		},
+		{
+			name: "nested attributes",
+			text: "[bold]a[italic, underline]b[fg=#f00]c[/]d[/]e[/]f",
+			want: []StyledRun{
+				styled("a", DefaultColor, PaletteColor(4), AttrBold),
+				styled("b", DefaultColor, PaletteColor(4), AttrBold|AttrItalic|AttrUnderline),
+				styled("c", red, PaletteColor(4), AttrBold|AttrItalic|AttrUnderline),
+				styled("d", DefaultColor, PaletteColor(4), AttrBold|AttrItalic|AttrUnderline),
+				styled("e", DefaultColor, PaletteColor(4), AttrBold),
+				styled("f", DefaultColor, PaletteColor(4), NoAttributes),
+			},
+		},
+		{
+			name: "unclosed tag at the end is fine",
+			text: "[dim]x",
+			want: []StyledRun{styled("x", DefaultColor, PaletteColor(4), AttrDim)},
+		},
+		{
+			name: "empty",
+			text: "[bold][/]",
+			want: []StyledRun{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseMarkup(test.text, base)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("ParseMarkup(%q)\ngot  %+v\nwant %+v", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseMarkupErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "unterminated tag", text: "a[bold"},
+		{name: "unmatched close", text: "a[/]"},
+		{name: "extra close", text: "[bold]a[/][/]"},
+		{name: "unknown attribute", text: "[shiny]a"},
+		{name: "unknown key", text: "[color=#fff]a"},
+		{name: "invalid color", text: "[fg=#ggg]a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseMarkup(test.text, StyledRun{})
+			if !errors.Is(err, ErrInvalidMarkup) {
+				t.Fatalf("ParseMarkup(%q) returned %v, want ErrInvalidMarkup", test.text, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package gui
+
+import (
+	"strings"
+	"sync"
+)
+
+type (
+	// MemoryBackend хранит экран в памяти и не требует терминала. Используется в тестах через пакет guitest
+	MemoryBackend struct {
+		mutex sync.Mutex
+
+		width  int
+		height int
+
+		backCells  []Cell
+		frontCells []Cell
+
+		cursorX int
+		cursorY int
+
+		outputMode OutputMode
+
+		syncs int
+		// строки, выведенные через SyncRows, в порядке вызовов
+		syncedRows []Rect
+
+		// всё, что записано через WriteRaw
+		raw []byte
+
+		closeChannel chan struct{}
+		closeOnce    sync.Once
+	}
+)
+
+const (
+	cursorHidden int = -1
+)
+
+func NewMemoryBackend(width, height int) *MemoryBackend {
+	backCells := newCells(width*height, DefaultCell)
+	frontCells := newCells(width*height, DefaultCell)
+
+	closeChannel := make(chan struct{})
+
+	backend := MemoryBackend{
+		width:        width,
+		height:       height,
+		backCells:    backCells,
+		frontCells:   frontCells,
+		cursorX:      cursorHidden,
+		cursorY:      cursorHidden,
+		outputMode:   OutputRGB,
+		syncs:        0,
+		syncedRows:   nil,
+		raw:          nil,
+		closeChannel: closeChannel,
+	}
+
+	return &backend
+}
+
+func (b *MemoryBackend) Init() error {
+	return nil
+}
+
+func (b *MemoryBackend) Close() {
+	b.closeOnce.Do(func() {
+		close(b.closeChannel)
+	})
+}
+
+func (b *MemoryBackend) Size() (int, int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.width, b.height
+}
+
+func (b *MemoryBackend) SetCell(x, y int, cell Cell) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return
+	}
+
+	// цвета хранятся такими, какими их покажет терминал в текущем режиме
+	cell.Foreground = cell.Foreground.Quantize(b.outputMode)
+	cell.Background = cell.Background.Quantize(b.outputMode)
+
+	b.backCells[y*b.width+x] = cell
+}
+
+func (b *MemoryBackend) Clear(cell Cell) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	cell.Foreground = cell.Foreground.Quantize(b.outputMode)
+	cell.Background = cell.Background.Quantize(b.outputMode)
+
+	for i := range b.backCells {
+		b.backCells[i] = cell
+	}
+
+	return nil
+}
+
+func (b *MemoryBackend) Flush() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	copy(b.frontCells, b.backCells)
+
+	return nil
+}
+
+// Sync выводит экран так же, как Flush, и считает вызовы, см. Syncs
+func (b *MemoryBackend) Sync() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	copy(b.frontCells, b.backCells)
+	b.syncs++
+
+	return nil
+}
+
+// SyncRows выводит строки так же, как Flush, и запоминает их, см. SyncedRows
+func (b *MemoryBackend) SyncRows(y, height int) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	start := min(max(y, 0), b.height) * b.width
+	end := min(max(y+height, 0), b.height) * b.width
+	copy(b.frontCells[start:end], b.backCells[start:end])
+
+	b.syncedRows = append(b.syncedRows, NewRect(0, y, b.width, height))
+
+	return nil
+}
+
+func (b *MemoryBackend) WriteRaw(data []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.raw = append(b.raw, data...)
+
+	return nil
+}
+
+// события в тестах подаются через Screen.PostEvent, поэтому PollEvent только ждёт закрытия
+func (b *MemoryBackend) PollEvent() Event {
+	<-b.closeChannel
+
+	return nil
+}
+
+func (b *MemoryBackend) SetCursor(x, y int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.cursorX = x
+	b.cursorY = y
+}
+
+func (b *MemoryBackend) HideCursor() {
+	b.SetCursor(cursorHidden, cursorHidden)
+}
+
+func (b *MemoryBackend) SetOutputMode(outputMode OutputMode) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.outputMode = outputMode
+}
+
+// test util
+
+// Resize меняет размер экрана и очищает оба буфера. EventResize нужно отправить отдельно
+func (b *MemoryBackend) Resize(width, height int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.width = width
+	b.height = height
+	b.backCells = newCells(width*height, DefaultCell)
+	b.frontCells = newCells(width*height, DefaultCell)
+}
+
+// Cells возвращает копию экрана на момент последнего Flush, построчно
+func (b *MemoryBackend) Cells() []Cell {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	cells := make([]Cell, len(b.frontCells))
+	copy(cells, b.frontCells)
+
+	return cells
+}
+
+func (b *MemoryBackend) Cell(x, y int) Cell {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return DefaultCell
+	}
+
+	cell := b.frontCells[y*b.width+x]
+
+	return cell
+}
+
+func (b *MemoryBackend) OutputMode() OutputMode {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.outputMode
+}
+
+// Syncs возвращает, сколько раз вызывался Sync: через него экран выводит Redraw
+func (b *MemoryBackend) Syncs() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.syncs
+}
+
+// SyncedRows возвращает строки, выведенные через SyncRows, как прямоугольники во всю ширину экрана
+func (b *MemoryBackend) SyncedRows() []Rect {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	syncedRows := make([]Rect, len(b.syncedRows))
+	copy(syncedRows, b.syncedRows)
+
+	return syncedRows
+}
+
+// Raw возвращает копию всего, что записано через WriteRaw: команды картинок Kitty и Sixel
+func (b *MemoryBackend) Raw() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	raw := make([]byte, len(b.raw))
+	copy(raw, b.raw)
+
+	return raw
+}
+
+// ResetRaw забывает записанное через WriteRaw, чтобы проверить вывод одного Flush
+func (b *MemoryBackend) ResetRaw() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.raw = nil
+}
+
+func (b *MemoryBackend) Cursor() (int, int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.cursorX, b.cursorY
+}
+
+// String возвращает символы экрана на момент последнего Flush, строки разделены '\n'
+func (b *MemoryBackend) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	builder := strings.Builder{}
+	for y := range b.height {
+		if y > 0 {
+			builder.WriteByte('\n')
+		}
+
+		for x := range b.width {
+			cell := b.frontCells[y*b.width+x]
+			writeCellSymbol(&builder, cell)
+		}
+	}
+
+	return builder.String()
+}
+
+// writeCellSymbol пишет кластер клетки так, как его покажет терминал: правая половина широкого символа пропускается
+func writeCellSymbol(builder *strings.Builder, cell Cell) {
+	switch cell.Symbol {
+	case ContinuationSymbol:
+		return
+	case 0:
+		builder.WriteRune(DefaultSymbol)
+	default:
+		builder.WriteRune(cell.Symbol)
+		builder.WriteString(cell.Combining)
+	}
+}
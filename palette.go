@@ -0,0 +1,220 @@
+package gui
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+type (
+	lab struct {
+		L float64
+		A float64
+		B float64
+	}
+)
+
+var (
+	// первые 16 цветов - значения xterm по умолчанию
+	ansiPalette    [256]Color = newANSIPalette()
+	ansiPaletteLab [256]lab   = newANSIPaletteLab()
+
+	// результат подбора запоминается в таблице постоянного размера: картинки дают много разных цветов,
+	// и кэш без ограничения рос бы с каждой из них. Запись вытесняет прежнюю с тем же номером ячейки
+	paletteIndexCache [1 << paletteCacheBits]atomic.Uint64
+)
+
+const (
+	paletteCacheBits int = 14
+	// в записи кэша номер палитры лежит в младших 8 битах, ключ - выше, старший бит отличает занятую запись
+	paletteEntryUsed  uint64 = 1 << 63
+	paletteEntryIndex uint64 = 0xff
+)
+
+func newANSIPalette() [256]Color {
+	var palette [256]Color
+
+	standardColors := [16]Color{
+		{R: 0, G: 0, B: 0},
+		{R: 205, G: 0, B: 0},
+		{R: 0, G: 205, B: 0},
+		{R: 205, G: 205, B: 0},
+		{R: 0, G: 0, B: 238},
+		{R: 205, G: 0, B: 205},
+		{R: 0, G: 205, B: 205},
+		{R: 229, G: 229, B: 229},
+		{R: 127, G: 127, B: 127},
+		{R: 255, G: 0, B: 0},
+		{R: 0, G: 255, B: 0},
+		{R: 255, G: 255, B: 0},
+		{R: 92, G: 92, B: 255},
+		{R: 255, G: 0, B: 255},
+		{R: 0, G: 255, B: 255},
+		{R: 255, G: 255, B: 255},
+	}
+	copy(palette[:16], standardColors[:])
+
+	// куб 6x6x6
+	cubeLevels := [6]int{0, 95, 135, 175, 215, 255}
+	for i := range 216 {
+		palette[16+i] = Color{
+			R: cubeLevels[i/36],
+			G: cubeLevels[i/6%6],
+			B: cubeLevels[i%6],
+		}
+	}
+
+	// 24 оттенка серого
+	for i := range 24 {
+		level := 8 + 10*i
+		palette[232+i] = Color{
+			R: level,
+			G: level,
+			B: level,
+		}
+	}
+
+	return palette
+}
+
+func newANSIPaletteLab() [256]lab {
+	var paletteLab [256]lab
+	for i := range ansiPalette {
+		paletteLab[i] = ansiPalette[i].toLab()
+	}
+
+	return paletteLab
+}
+
+// PaletteColor возвращает RGB цвета с номером index из 256-цветной палитры xterm
+func PaletteColor(index int) Color {
+	if index < 0 || index >= len(ansiPalette) {
+		return DefaultColor
+	}
+
+	return ansiPalette[index]
+}
+
+// PaletteIndex возвращает номер ближайшего по восприятию цвета палитры режима outputMode.
+// В Output256 выбираются только номера 16-255: первые 16 цветов терминалы часто переопределяют.
+// Для OutputRGB и OutputAuto возвращается номер в 256-цветной палитре
+func (c Color) PaletteIndex(outputMode OutputMode) int {
+	first, last := paletteRange(outputMode)
+
+	key := paletteCacheKey(c, last)
+	slot := &paletteIndexCache[key*0x9E3779B97F4A7C15>>(64-paletteCacheBits)]
+
+	entry := slot.Load()
+	if entry&^paletteEntryIndex == key<<8|paletteEntryUsed {
+		return int(entry & paletteEntryIndex)
+	}
+
+	index := nearestPaletteIndex(c, first, last)
+	slot.Store(key<<8 | paletteEntryUsed | uint64(index))
+
+	return index
+}
+
+// Quantize заменяет цвет ближайшим, который может показать терминал в режиме outputMode
+func (c Color) Quantize(outputMode OutputMode) Color {
+	if c == DefaultColor || outputMode == OutputRGB || outputMode == OutputAuto {
+		return c
+	}
+
+	index := c.PaletteIndex(outputMode)
+	color := ansiPalette[index]
+
+	return color
+}
+
+func paletteRange(outputMode OutputMode) (int, int) {
+	switch outputMode {
+	case Output8:
+		return 0, 8
+	case Output16:
+		return 0, 16
+	default:
+		return 16, 256
+	}
+}
+
+// paletteCacheKey упаковывает цвет и конец диапазона палитры, начало диапазона определяется концом.
+// Каналы вне 0-255 подбор всё равно обрезает, поэтому они обрезаются и в ключе
+func paletteCacheKey(color Color, last int) uint64 {
+	r := uint64(min(max(color.R, 0), 255))
+	g := uint64(min(max(color.G, 0), 255))
+	b := uint64(min(max(color.B, 0), 255))
+
+	return uint64(last)<<24 | r<<16 | g<<8 | b
+}
+
+func nearestPaletteIndex(color Color, first, last int) int {
+	lab := color.toLab()
+
+	nearestIndex := first
+	nearestDistance := math.Inf(1)
+	for i := first; i < last; i++ {
+		distance := lab.distance(ansiPaletteLab[i])
+		if distance < nearestDistance {
+			nearestIndex = i
+			nearestDistance = distance
+		}
+	}
+
+	return nearestIndex
+}
+
+// CIELAB: евклидово расстояние в нём близко к разнице, которую видит глаз
+
+func (c Color) toLab() lab {
+	x, y, z := c.toXYZ()
+
+	// белая точка D65
+	fx := labF(x / 0.95047)
+	fy := labF(y / 1.00000)
+	fz := labF(z / 1.08883)
+
+	l := lab{
+		L: 116*fy - 16,
+		A: 500 * (fx - fy),
+		B: 200 * (fy - fz),
+	}
+
+	return l
+}
+
+func (c Color) toXYZ() (float64, float64, float64) {
+	r := srgbToLinear(c.R)
+	g := srgbToLinear(c.G)
+	b := srgbToLinear(c.B)
+
+	x := 0.4124*r + 0.3576*g + 0.1805*b
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := 0.0193*r + 0.1192*g + 0.9505*b
+
+	return x, y, z
+}
+
+func srgbToLinear(channel int) float64 {
+	value := float64(min(max(channel, 0), 255)) / 255
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	if t > 216.0/24389.0 {
+		return math.Cbrt(t)
+	}
+
+	return (24389.0/27.0*t + 16) / 116
+}
+
+func (l lab) distance(other lab) float64 {
+	dl := l.L - other.L
+	da := l.A - other.A
+	db := l.B - other.B
+
+	return dl*dl + da*da + db*db
+}
@@ -0,0 +1,32 @@
+package gui
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestPaletteIndexCache(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 2))
+
+	for range 5000 {
+		color := Color{R: random.IntN(256), G: random.IntN(256), B: random.IntN(256)}
+
+		for _, outputMode := range []OutputMode{Output8, Output16, Output256} {
+			first, last := paletteRange(outputMode)
+			want := nearestPaletteIndex(color, first, last)
+
+			// второй вызов берёт номер из кэша, если ячейку не заняли другим цветом
+			for range 2 {
+				got := color.PaletteIndex(outputMode)
+				if got != want {
+					t.Fatalf("%+v.PaletteIndex(%v) = %d, want %d", color, outputMode, got, want)
+				}
+			}
+		}
+	}
+
+	clipped := Color{R: 300, G: -5, B: 128}.PaletteIndex(Output256)
+	if want := (Color{R: 255, G: 0, B: 128}).PaletteIndex(Output256); clipped != want {
+		t.Fatalf("out of range channels gave %d, want %d", clipped, want)
+	}
+}
@@ -0,0 +1,292 @@
+package gui
+
+type (
+	// PixelMode задаёт, сколько точек PixelCanvas приходится на одну клетку
+	PixelMode int
+
+	// PixelCanvas - холст из точек, которые DrawPixels рисует символами Брайля или полублоками.
+	// Координаты точек начинаются с (0, 0), точки вне холста не рисуются
+	PixelCanvas struct {
+		mode   PixelMode
+		width  int
+		height int
+		pixels []Color
+		set    []bool
+	}
+)
+
+const (
+	// PixelBraille - 2×4 точки в клетке, у всех точек клетки один цвет
+	PixelBraille PixelMode = iota
+	// PixelHalfBlock - 1×2 точки в клетке, у верхней и нижней точки свои цвета
+	PixelHalfBlock
+)
+
+const (
+	brailleBlank rune = 0x2800
+
+	upperHalfBlock rune = '▀'
+	lowerHalfBlock rune = '▄'
+)
+
+var (
+	// биты точек символа Брайля по [y][x] внутри клетки
+	brailleDots [4][2]rune = [4][2]rune{
+		{0x01, 0x08},
+		{0x02, 0x10},
+		{0x04, 0x20},
+		{0x40, 0x80},
+	}
+)
+
+func NewPixelCanvas(width, height int, mode PixelMode) *PixelCanvas {
+	width = max(width, 0)
+	height = max(height, 0)
+
+	p := PixelCanvas{
+		mode:   mode,
+		width:  width,
+		height: height,
+		pixels: make([]Color, width*height),
+		set:    make([]bool, width*height),
+	}
+
+	return &p
+}
+
+// cellSize возвращает размер клетки в точках
+func (m PixelMode) cellSize() (int, int) {
+	if m == PixelHalfBlock {
+		return 1, 2
+	}
+
+	return 2, 4
+}
+
+// Size возвращает размер холста в точках
+func (p *PixelCanvas) Size() (int, int) {
+	return p.width, p.height
+}
+
+// CellSize возвращает, сколько клеток займёт холст
+func (p *PixelCanvas) CellSize() (int, int) {
+	cellWidth, cellHeight := p.mode.cellSize()
+
+	return (p.width + cellWidth - 1) / cellWidth, (p.height + cellHeight - 1) / cellHeight
+}
+
+func (p *PixelCanvas) SetPixel(x, y int, color Color) {
+	if x < 0 || y < 0 || x >= p.width || y >= p.height {
+		return
+	}
+
+	index := y*p.width + x
+	p.pixels[index] = color
+	p.set[index] = true
+}
+
+func (p *PixelCanvas) ClearPixel(x, y int) {
+	if x < 0 || y < 0 || x >= p.width || y >= p.height {
+		return
+	}
+
+	index := y*p.width + x
+	p.pixels[index] = Color{}
+	p.set[index] = false
+}
+
+// Pixel возвращает цвет точки и false, если точка не закрашена
+func (p *PixelCanvas) Pixel(x, y int) (Color, bool) {
+	if x < 0 || y < 0 || x >= p.width || y >= p.height {
+		return Color{}, false
+	}
+
+	index := y*p.width + x
+
+	return p.pixels[index], p.set[index]
+}
+
+func (p *PixelCanvas) Clear() {
+	clear(p.pixels)
+	clear(p.set)
+}
+
+// DrawLine рисует отрезок от (x0, y0) до (x1, y1) включительно
+func (p *PixelCanvas) DrawLine(x0, y0, x1, y1 int, color Color) {
+	// алгоритм Брезенхэма для всех октантов
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	stepX := 1
+	if x0 > x1 {
+		stepX = -1
+	}
+	stepY := 1
+	if y0 > y1 {
+		stepY = -1
+	}
+
+	err := dx + dy
+	for {
+		p.SetPixel(x0, y0, color)
+
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+
+		doubledErr := 2 * err
+		if doubledErr >= dy {
+			err += dy
+			x0 += stepX
+		}
+		if doubledErr <= dx {
+			err += dx
+			y0 += stepY
+		}
+	}
+}
+
+// DrawCircle рисует окружность радиуса radius с центром в (centerX, centerY)
+func (p *PixelCanvas) DrawCircle(centerX, centerY, radius int, color Color) {
+	if radius < 0 {
+		return
+	}
+
+	// алгоритм средней точки: строится одна восьмая окружности, остальное отражается
+	x := radius
+	y := 0
+	err := 1 - radius
+	for x >= y {
+		p.SetPixel(centerX+x, centerY+y, color)
+		p.SetPixel(centerX+y, centerY+x, color)
+		p.SetPixel(centerX-y, centerY+x, color)
+		p.SetPixel(centerX-x, centerY+y, color)
+		p.SetPixel(centerX-x, centerY-y, color)
+		p.SetPixel(centerX-y, centerY-x, color)
+		p.SetPixel(centerX+y, centerY-x, color)
+		p.SetPixel(centerX+x, centerY-y, color)
+
+		y++
+		if err < 0 {
+			err += 2*y + 1
+		} else {
+			x--
+			err += 2*(y-x) + 1
+		}
+	}
+}
+
+// draw
+
+// DrawPixels рисует pixels клетками, левая верхняя клетка холста попадает в (x, y).
+// Клетки без закрашенных точек не рисуются, поэтому холст можно накладывать поверх нарисованного.
+// В PixelBraille цвет клетки - самый частый цвет её точек
+func (ctx *Context) DrawPixels(x, y int, pixels *PixelCanvas, background Color) {
+	cellsWidth, cellsHeight := pixels.CellSize()
+
+	for cellY := range cellsHeight {
+		for cellX := range cellsWidth {
+			cell, ok := pixels.cell(cellX, cellY, background)
+			if !ok {
+				continue
+			}
+
+			ctx.setLocalCell(x+cellX, y+cellY, cell)
+		}
+	}
+}
+
+// cell собирает клетку (cellX, cellY) холста. false, если в ней нет закрашенных точек
+func (p *PixelCanvas) cell(cellX, cellY int, background Color) (Cell, bool) {
+	if p.mode == PixelHalfBlock {
+		return p.halfBlockCell(cellX, cellY, background)
+	}
+
+	return p.brailleCell(cellX, cellY, background)
+}
+
+func (p *PixelCanvas) halfBlockCell(cellX, cellY int, background Color) (Cell, bool) {
+	top, topSet := p.Pixel(cellX, cellY*2)
+	bottom, bottomSet := p.Pixel(cellX, cellY*2+1)
+
+	cell := Cell{
+		Background: background,
+	}
+
+	switch {
+	case topSet && bottomSet:
+		cell.Symbol = upperHalfBlock
+		cell.Foreground = top
+		cell.Background = bottom
+	case topSet:
+		cell.Symbol = upperHalfBlock
+		cell.Foreground = top
+	case bottomSet:
+		cell.Symbol = lowerHalfBlock
+		cell.Foreground = bottom
+	default:
+		return Cell{}, false
+	}
+
+	return cell, true
+}
+
+func (p *PixelCanvas) brailleCell(cellX, cellY int, background Color) (Cell, bool) {
+	symbol := brailleBlank
+
+	// у клетки Брайля один цвет, поэтому выбирается тот, которым закрашено больше точек
+	var colors [8]Color
+	var counts [8]int
+	colorCount := 0
+
+	for dotY := range brailleDots {
+		for dotX := range brailleDots[dotY] {
+			color, ok := p.Pixel(cellX*2+dotX, cellY*4+dotY)
+			if !ok {
+				continue
+			}
+
+			symbol |= brailleDots[dotY][dotX]
+
+			found := false
+			for i := range colorCount {
+				if colors[i] == color {
+					counts[i]++
+					found = true
+					break
+				}
+			}
+			if !found {
+				colors[colorCount] = color
+				counts[colorCount] = 1
+				colorCount++
+			}
+		}
+	}
+
+	if colorCount == 0 {
+		return Cell{}, false
+	}
+
+	dominant := 0
+	for i := 1; i < colorCount; i++ {
+		if counts[i] > counts[dominant] {
+			dominant = i
+		}
+	}
+
+	cell := Cell{
+		Symbol:     symbol,
+		Foreground: colors[dominant],
+		Background: background,
+	}
+
+	return cell, true
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
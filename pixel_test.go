@@ -0,0 +1,249 @@
+package gui
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestBrailleCell(t *testing.T) {
+	red := Color{R: 255}
+	blue := Color{B: 255}
+	background := PaletteColor(0)
+
+	tests := []struct {
+		name   string
+		pixels []cellPosition
+		colors []Color
+		cellX  int
+		cellY  int
+		want   Cell
+	}{
+		{
+			name:   "top left",
+			pixels: []cellPosition{{X: 0, Y: 0}},
+			colors: []Color{red},
+			want:   Cell{Symbol: 0x2801, Foreground: red, Background: background},
+		},
+		{
+			name:   "bottom right",
+			pixels: []cellPosition{{X: 1, Y: 3}},
+			colors: []Color{red},
+			want:   Cell{Symbol: 0x2880, Foreground: red, Background: background},
+		},
+		{
+			name:   "left column",
+			pixels: []cellPosition{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 0, Y: 3}},
+			colors: []Color{red, red, red, red},
+			want:   Cell{Symbol: 0x2847, Foreground: red, Background: background},
+		},
+		{
+			name:   "right column",
+			pixels: []cellPosition{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 1, Y: 3}},
+			colors: []Color{red, red, red, red},
+			want:   Cell{Symbol: 0x28b8, Foreground: red, Background: background},
+		},
+		{
+			name:   "dominant color",
+			pixels: []cellPosition{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}},
+			colors: []Color{red, blue, blue},
+			want:   Cell{Symbol: 0x280b, Foreground: blue, Background: background},
+		},
+		{
+			name:   "second cell",
+			pixels: []cellPosition{{X: 1, Y: 3}, {X: 3, Y: 7}},
+			colors: []Color{red, blue},
+			cellX:  1,
+			cellY:  1,
+			want:   Cell{Symbol: 0x2880, Foreground: blue, Background: background},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pixels := NewPixelCanvas(4, 8, PixelBraille)
+			for i, position := range test.pixels {
+				pixels.SetPixel(position.X, position.Y, test.colors[i])
+			}
+
+			got, ok := pixels.cell(test.cellX, test.cellY, background)
+			if !ok || got != test.want {
+				t.Fatalf("cell(%d, %d) = %+v, %v, want %+v", test.cellX, test.cellY, got, ok, test.want)
+			}
+		})
+	}
+
+	pixels := NewPixelCanvas(4, 8, PixelBraille)
+	pixels.SetPixel(0, 0, red)
+
+	_, ok := pixels.cell(1, 0, background)
+	if ok {
+		t.Fatal("cell without pixels is drawn")
+	}
+}
+
+func TestHalfBlockCell(t *testing.T) {
+	red := Color{R: 255}
+	blue := Color{B: 255}
+	background := PaletteColor(0)
+
+	tests := []struct {
+		name   string
+		top    bool
+		bottom bool
+		want   Cell
+	}{
+		{
+			name: "top",
+			top:  true,
+			want: Cell{Symbol: upperHalfBlock, Foreground: red, Background: background},
+		},
+		{
+			name:   "bottom",
+			bottom: true,
+			want:   Cell{Symbol: lowerHalfBlock, Foreground: blue, Background: background},
+		},
+		{
+			name:   "both",
+			top:    true,
+			bottom: true,
+			want:   Cell{Symbol: upperHalfBlock, Foreground: red, Background: blue},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pixels := NewPixelCanvas(1, 2, PixelHalfBlock)
+			if test.top {
+				pixels.SetPixel(0, 0, red)
+			}
+			if test.bottom {
+				pixels.SetPixel(0, 1, blue)
+			}
+
+			got, ok := pixels.cell(0, 0, background)
+			if !ok || got != test.want {
+				t.Fatalf("cell(0, 0) = %+v, %v, want %+v", got, ok, test.want)
+			}
+		})
+	}
+
+	pixels := NewPixelCanvas(1, 2, PixelHalfBlock)
+
+	_, ok := pixels.cell(0, 0, background)
+	if ok {
+		t.Fatal("cell without pixels is drawn")
+	}
+}
+
+func TestDrawLine(t *testing.T) {
+	got := drawnPixels(NewPixelCanvas(5, 3, PixelBraille), func(pixels *PixelCanvas) {
+		pixels.DrawLine(0, 0, 4, 2, Color{})
+	})
+	want := []cellPosition{{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 2}, {X: 4, Y: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DrawLine(0, 0, 4, 2) = %v, want %v", got, want)
+	}
+
+	// по концу отрезка в каждом октанте вокруг (5, 5), а также по осям и диагоналям
+	ends := []cellPosition{
+		{X: 9, Y: 7}, {X: 7, Y: 9}, {X: 3, Y: 9}, {X: 1, Y: 7},
+		{X: 1, Y: 3}, {X: 3, Y: 1}, {X: 7, Y: 1}, {X: 9, Y: 3},
+		{X: 9, Y: 5}, {X: 5, Y: 9}, {X: 1, Y: 5}, {X: 5, Y: 1},
+		{X: 9, Y: 9}, {X: 1, Y: 1}, {X: 5, Y: 5},
+	}
+
+	for _, end := range ends {
+		line := drawnPixels(NewPixelCanvas(11, 11, PixelBraille), func(pixels *PixelCanvas) {
+			pixels.DrawLine(5, 5, end.X, end.Y, Color{})
+		})
+
+		// по главной оси отрезок занимает ровно одну точку на каждую координату
+		length := max(abs(end.X-5), abs(end.Y-5)) + 1
+		if len(line) != length {
+			t.Errorf("DrawLine(5, 5, %d, %d) drew %d pixels, want %d: %v", end.X, end.Y, len(line), length, line)
+			continue
+		}
+
+		if !slices.Contains(line, cellPosition{X: 5, Y: 5}) || !slices.Contains(line, end) {
+			t.Errorf("DrawLine(5, 5, %d, %d) = %v, misses an end", end.X, end.Y, line)
+		}
+
+		// отрезок в обратную сторону проходит по тем же точкам
+		reversed := drawnPixels(NewPixelCanvas(11, 11, PixelBraille), func(pixels *PixelCanvas) {
+			pixels.DrawLine(end.X, end.Y, 5, 5, Color{})
+		})
+		if len(reversed) != length {
+			t.Errorf("DrawLine(%d, %d, 5, 5) drew %d pixels, want %d", end.X, end.Y, len(reversed), length)
+		}
+	}
+}
+
+func TestDrawCircle(t *testing.T) {
+	got := drawnPixels(NewPixelCanvas(5, 5, PixelBraille), func(pixels *PixelCanvas) {
+		pixels.DrawCircle(2, 2, 2, Color{})
+	})
+	want := []cellPosition{
+		{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0},
+		{X: 0, Y: 1}, {X: 4, Y: 1},
+		{X: 0, Y: 2}, {X: 4, Y: 2},
+		{X: 0, Y: 3}, {X: 4, Y: 3},
+		{X: 1, Y: 4}, {X: 2, Y: 4}, {X: 3, Y: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DrawCircle(2, 2, 2) = %v, want %v", got, want)
+	}
+
+	// окружность симметрична относительно осей и диагоналей, то есть одинакова во всех октантах
+	circle := drawnPixels(NewPixelCanvas(21, 21, PixelBraille), func(pixels *PixelCanvas) {
+		pixels.DrawCircle(10, 10, 7, Color{})
+	})
+	for _, position := range circle {
+		x := position.X - 10
+		y := position.Y - 10
+
+		mirrors := []cellPosition{{X: -x, Y: y}, {X: x, Y: -y}, {X: y, Y: x}, {X: -y, Y: -x}}
+		for _, mirror := range mirrors {
+			if !slices.Contains(circle, cellPosition{X: mirror.X + 10, Y: mirror.Y + 10}) {
+				t.Fatalf("DrawCircle(10, 10, 7) has (%d, %d) but not its mirror (%d, %d)", x, y, mirror.X, mirror.Y)
+			}
+		}
+
+		distance := x*x + y*y
+		if distance < 6*6 || distance > 8*8 {
+			t.Fatalf("DrawCircle(10, 10, 7) has (%d, %d) too far from the radius", x, y)
+		}
+	}
+
+	single := drawnPixels(NewPixelCanvas(3, 3, PixelBraille), func(pixels *PixelCanvas) {
+		pixels.DrawCircle(1, 1, 0, Color{})
+	})
+	if !reflect.DeepEqual(single, []cellPosition{{X: 1, Y: 1}}) {
+		t.Fatalf("DrawCircle with radius 0 = %v, want the center", single)
+	}
+
+	none := drawnPixels(NewPixelCanvas(3, 3, PixelBraille), func(pixels *PixelCanvas) {
+		pixels.DrawCircle(1, 1, -1, Color{})
+	})
+	if len(none) != 0 {
+		t.Fatalf("DrawCircle with negative radius = %v, want nothing", none)
+	}
+}
+
+// drawnPixels возвращает точки, закрашенные draw, построчно
+func drawnPixels(pixels *PixelCanvas, draw func(pixels *PixelCanvas)) []cellPosition {
+	draw(pixels)
+
+	width, height := pixels.Size()
+	positions := []cellPosition{}
+	for y := range height {
+		for x := range width {
+			_, ok := pixels.Pixel(x, y)
+			if ok {
+				positions = append(positions, cellPosition{X: x, Y: y})
+			}
+		}
+	}
+
+	return positions
+}
@@ -0,0 +1,60 @@
+package gui
+
+type (
+	Rect struct {
+		X      int
+		Y      int
+		Width  int
+		Height int
+	}
+)
+
+func NewRect(x, y, width, height int) Rect {
+	rect := Rect{
+		X:      x,
+		Y:      y,
+		Width:  width,
+		Height: height,
+	}
+
+	return rect
+}
+
+func (r Rect) Empty() bool {
+	return r.Width <= 0 || r.Height <= 0
+}
+
+// Contains проверяет, попадает ли клетка (x, y) в прямоугольник
+func (r Rect) Contains(x, y int) bool {
+	return x >= r.X && y >= r.Y && x < r.X+r.Width && y < r.Y+r.Height
+}
+
+func (r Rect) Intersect(other Rect) Rect {
+	x := max(r.X, other.X)
+	y := max(r.Y, other.Y)
+	endX := min(r.X+r.Width, other.X+other.Width)
+	endY := min(r.Y+r.Height, other.Y+other.Height)
+
+	intersection := NewRect(x, y, max(endX-x, 0), max(endY-y, 0))
+
+	return intersection
+}
+
+// Union возвращает наименьший прямоугольник, содержащий оба. Пустые прямоугольники не учитываются
+func (r Rect) Union(other Rect) Rect {
+	if r.Empty() {
+		return other
+	}
+	if other.Empty() {
+		return r
+	}
+
+	x := min(r.X, other.X)
+	y := min(r.Y, other.Y)
+	endX := max(r.X+r.Width, other.X+other.Width)
+	endY := max(r.Y+r.Height, other.Y+other.Height)
+
+	union := NewRect(x, y, endX-x, endY-y)
+
+	return union
+}
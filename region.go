@@ -0,0 +1,61 @@
+package gui
+
+type (
+	// region переводит локальные координаты Context в координаты слоя и отсекает всё вне clip
+	region struct {
+		originX int
+		originY int
+		clip    Rect
+		clipped bool
+	}
+)
+
+func (r region) toLayer(x, y int) (int, int, bool) {
+	x += r.originX
+	y += r.originY
+
+	if r.clipped && !r.clip.Contains(x, y) {
+		return x, y, false
+	}
+
+	return x, y, true
+}
+
+// sub возвращает область rect, заданную в локальных координатах r
+func (r region) sub(rect Rect) region {
+	clip := NewRect(rect.X+r.originX, rect.Y+r.originY, rect.Width, rect.Height)
+	if r.clipped {
+		clip = clip.Intersect(r.clip)
+	}
+
+	subRegion := region{
+		originX: rect.X + r.originX,
+		originY: rect.Y + r.originY,
+		clip:    clip,
+		clipped: true,
+	}
+
+	return subRegion
+}
+
+// Sub возвращает Context, который рисует только внутри rect, а его (0, 0) совпадает с (rect.X, rect.Y).
+// Вложенные Sub отсекаются и родительскими прямоугольниками. Слой и область сохраняются при Layer и HUD
+func (ctx *Context) Sub(rect Rect) *Context {
+	subContext := *ctx
+	subContext.region = ctx.region.sub(rect)
+
+	return &subContext
+}
+
+// Region возвращает видимую часть области Sub в локальных координатах. false, если ctx не ограничен
+func (ctx *Context) Region() (Rect, bool) {
+	if !ctx.region.clipped {
+		return Rect{}, false
+	}
+
+	localRect := ctx.region.clip
+	localRect.X -= ctx.region.originX
+	localRect.Y -= ctx.region.originY
+
+	return localRect, true
+}
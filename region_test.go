@@ -0,0 +1,33 @@
+package gui_test
+
+import (
+	"testing"
+
+	"github.com/gggallahad/gui"
+	"github.com/gggallahad/gui/guitest"
+)
+
+func TestHugeSubBoundsAndClear(t *testing.T) {
+	h := guitest.NewStarted(t, 4, 2)
+
+	var bounds gui.Rect
+	var ok bool
+	h.Draw(t, func(ctx *gui.Context) {
+		ctx.SetText(0, 0, "ab", gui.DefaultColor, gui.DefaultColor)
+		ctx.SetCell(3, 1, gui.Cell{Symbol: 'c'})
+
+		// обход всех клеток такой области не закончился бы никогда
+		sub := ctx.Sub(gui.NewRect(-1<<40, -1<<40, 1<<41, 1<<41))
+		bounds, ok = sub.Bounds()
+
+		keep := ctx.Sub(gui.NewRect(1, 0, 3, 2))
+		keep.Clear()
+	})
+
+	want := gui.NewRect(1<<40, 1<<40, 4, 2)
+	if !ok || bounds != want {
+		t.Fatalf("Bounds() = %v, %v, want %v", bounds, ok, want)
+	}
+
+	h.AssertString(t, "a   \n    ")
+}
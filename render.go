@@ -0,0 +1,251 @@
+package gui
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+type (
+	// RenderStats описывает последний Flush
+	RenderStats struct {
+		CellsChanged int
+		// EstimatedBytes - оценка объёма управляющих последовательностей и символов, которые уходят в терминал.
+		// Она считается по изменившимся клеткам, а не по выводу бэкенда: точное число знает только он.
+		// Команды картинок PlaceImage учитываются точно
+		EstimatedBytes int
+	}
+
+	frame struct {
+		width  int
+		height int
+		cells  []Cell
+	}
+
+	// renderer хранит два кадра: back собирается из хранилища, front - то, что уже выведено
+	renderer struct {
+		back  *frame
+		front *frame
+
+		// после изменения размера или Redraw экран очищается целиком перед выводом
+		clearPending bool
+		// после Redraw бэкенд выводит весь экран заново, не сравнивая его с тем, что считает уже выведенным
+		syncPending bool
+		// строки под стёртыми картинками Sixel, которые бэкенд выводит заново после Flush
+		syncRows []Rect
+
+		outputMode OutputMode
+		stats      RenderStats
+	}
+)
+
+func newFrame(width, height int, cell Cell) *frame {
+	cells := newCells(width*height, cell)
+
+	f := frame{
+		width:  width,
+		height: height,
+		cells:  cells,
+	}
+
+	return &f
+}
+
+func (f *frame) fill(cell Cell) {
+	for i := range f.cells {
+		f.cells[i] = cell
+	}
+}
+
+func (f *frame) set(x, y int, cell Cell) {
+	if x < 0 || y < 0 || x >= f.width || y >= f.height {
+		return
+	}
+
+	f.cells[y*f.width+x] = cell
+}
+
+func (f *frame) get(x, y int) Cell {
+	cell := f.cells[y*f.width+x]
+
+	return cell
+}
+
+func newRenderer() *renderer {
+	back := newFrame(0, 0, DefaultCell)
+	front := newFrame(0, 0, DefaultCell)
+
+	r := renderer{
+		back:         back,
+		front:        front,
+		clearPending: true,
+		syncPending:  false,
+		syncRows:     nil,
+		outputMode:   OutputRGB,
+		stats:        RenderStats{},
+	}
+
+	return &r
+}
+
+func (r *renderer) resize(width, height int) {
+	if width == r.back.width && height == r.back.height {
+		return
+	}
+
+	r.back = newFrame(width, height, DefaultCell)
+	r.front = newFrame(width, height, DefaultCell)
+	r.clearPending = true
+}
+
+func (r *renderer) invalidate() {
+	r.clearPending = true
+	r.syncPending = true
+}
+
+// present выводит в backend клетки back, отличающиеся от front, и делает back новым front
+func (r *renderer) present(backend Backend, defaultCell Cell) error {
+	stats := RenderStats{}
+
+	if r.clearPending {
+		err := backend.Clear(defaultCell)
+		if err != nil {
+			return err
+		}
+
+		r.front.fill(defaultCell)
+		r.clearPending = false
+
+		stats.EstimatedBytes += len(clearSequence)
+	}
+
+	lastX, lastY := -1, -1
+	var lastCell *Cell
+	for y := range r.back.height {
+		for x := range r.back.width {
+			cell := r.back.get(x, y)
+			if cell == r.front.get(x, y) {
+				continue
+			}
+
+			backend.SetCell(x, y, cell)
+			r.front.set(x, y, cell)
+
+			stats.CellsChanged++
+			stats.EstimatedBytes += r.cellCost(x, y, lastX, lastY, cell, lastCell)
+
+			lastX, lastY = x, y
+			lastCell = &cell
+		}
+	}
+
+	err := r.flush(backend)
+	if err != nil {
+		return err
+	}
+
+	r.stats = stats
+
+	return nil
+}
+
+func (r *renderer) flush(backend Backend) error {
+	syncRows := r.syncRows
+	r.syncRows = nil
+
+	if r.syncPending {
+		r.syncPending = false
+
+		return backend.Sync()
+	}
+
+	err := backend.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, rowsRect := range syncRows {
+		err := backend.SyncRows(rowsRect.Y, rowsRect.Height)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// util
+
+const (
+	clearSequence string = "\x1b[H\x1b[2J"
+)
+
+// cellCost оценивает вывод клетки: перемещение курсора, если клетка не продолжает предыдущую,
+// SGR, если начертание сменилось, и сам символ
+func (r *renderer) cellCost(x, y, lastX, lastY int, cell Cell, lastCell *Cell) int {
+	if cell.Symbol == ContinuationSymbol {
+		return 0
+	}
+
+	cost := 0
+
+	if y != lastY || x != lastX+1 {
+		// ESC [ y ; x H
+		cost += 4 + digitCount(y+1) + digitCount(x+1)
+	}
+
+	if lastCell == nil || !sameStyle(cell, *lastCell) {
+		cost += r.sgrLength(cell)
+	}
+
+	symbol := cell.Symbol
+	if symbol == 0 {
+		symbol = DefaultSymbol
+	}
+	cost += utf8.RuneLen(symbol) + len(cell.Combining)
+
+	return cost
+}
+
+// sgrLength - длина ESC [ 0 ; ... m, которой выставляется начертание клетки
+func (r *renderer) sgrLength(cell Cell) int {
+	// ESC [ 0 ... m
+	length := 4
+
+	for attribute := AttrBold; attribute <= AttrDim; attribute <<= 1 {
+		if cell.Attributes.Has(attribute) {
+			// ; n
+			length += 2
+		}
+	}
+
+	length += r.colorLength(cell.Foreground)
+	length += r.colorLength(cell.Background)
+
+	return length
+}
+
+func (r *renderer) colorLength(color Color) int {
+	if color == DefaultColor {
+		return 0
+	}
+
+	switch r.outputMode {
+	case OutputRGB:
+		// ;38;2;r;g;b
+		return 8 + digitCount(color.R) + digitCount(color.G) + digitCount(color.B)
+	case Output256:
+		// ;38;5;n
+		return 6 + digitCount(color.PaletteIndex(r.outputMode))
+	default:
+		// ;3n или ;9n
+		return 3
+	}
+}
+
+func sameStyle(a, b Cell) bool {
+	return a.Foreground == b.Foreground && a.Background == b.Background && a.Attributes == b.Attributes
+}
+
+func digitCount(value int) int {
+	return len(strconv.Itoa(value))
+}
@@ -1,9 +1,5 @@
 package gui
 
-import (
-	"github.com/nsf/termbox-go"
-)
-
 type (
 	Screen struct {
 		initHandlers       []InitHandler
@@ -12,7 +8,16 @@ type (
 		globalPostwares    []Handler
 		handlers           map[State][]Handler
 
-		context *Context
+		dispatchMode DispatchMode
+		// обработчики DispatchConcurrent, которые ещё выполняются, и ожидающие Sync.
+		// Меняются только в горутине Run
+		handlersRunning int
+		idleWaiters     []chan struct{}
+
+		backend    Backend
+		outputMode OutputMode
+		graphics   GraphicsProtocol
+		context    *Context
 	}
 )
 
@@ -20,23 +25,40 @@ func NewScreen(screenConfig ...ScreenConfig) (*Screen, error) {
 	var config ScreenConfig
 	if len(screenConfig) != 0 {
 		config = screenConfig[0]
-	} else {
+	}
+
+	if config.DefaultCell == (Cell{}) {
 		config.DefaultCell = DefaultCell
 	}
 
+	if config.Backend == nil {
+		config.Backend = NewTermboxBackend()
+	}
+
 	handlers := make(map[State][]Handler)
 
-	context, err := newContext(config.DefaultCell)
+	context, err := newContext(config.Backend, config.DefaultCell)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.CellPixelWidth > 0 && config.CellPixelHeight > 0 {
+		context.graphics.cellWidth = config.CellPixelWidth
+		context.graphics.cellHeight = config.CellPixelHeight
+	}
+
 	screen := Screen{
 		initHandlers:       nil,
 		backgroundHandlers: nil,
 		globalMiddlewares:  nil,
 		globalPostwares:    nil,
 		handlers:           handlers,
+		dispatchMode:       config.DispatchMode,
+		handlersRunning:    0,
+		idleWaiters:        nil,
+		backend:            config.Backend,
+		outputMode:         config.OutputMode,
+		graphics:           config.Graphics,
 		context:            context,
 	}
 
@@ -63,37 +85,142 @@ func (s *Screen) BindHandlers(state State, handlers ...Handler) {
 	s.handlers[state] = handlers
 }
 
+// обработчики жизненного цикла получают второе состояние перехода:
+// enter - из какого пришли, exit - в какое уходим, pause - какое легло сверху, resume - какое снято
+
+func (s *Screen) BindEnterHandlers(state State, enterHandlers ...StateHandler) {
+	s.context.stateHandlers.enter[state] = enterHandlers
+}
+
+func (s *Screen) BindExitHandlers(state State, exitHandlers ...StateHandler) {
+	s.context.stateHandlers.exit[state] = exitHandlers
+}
+
+func (s *Screen) BindPauseHandlers(state State, pauseHandlers ...StateHandler) {
+	s.context.stateHandlers.pause[state] = pauseHandlers
+}
+
+func (s *Screen) BindResumeHandlers(state State, resumeHandlers ...StateHandler) {
+	s.context.stateHandlers.resume[state] = resumeHandlers
+}
+
 func (s *Screen) Run() {
+	uiContext := s.context.newUIContext()
+
 	for i := range s.initHandlers {
-		s.initHandlers[i](s.context)
+		s.initHandlers[i](uiContext)
 	}
 
 	for i := range s.backgroundHandlers {
 		go s.backgroundHandlers[i](s.context)
 	}
 
-	eventChannel := make(chan Event)
-
-	go s.getEvents(eventChannel)
+	go s.getEvents()
 
 RunLoop:
 	for {
 		select {
 		case <-s.context.killChannel:
 			break RunLoop
-		case event := <-eventChannel:
-			go s.handleEvent(event)
+		case <-s.context.messages.signal:
+			s.handleMessages(uiContext)
 		}
 	}
 
+	close(s.context.stopChannel)
 	s.context.Cancel()
 }
 
-func (s *Screen) getEvents(eventChannel chan<- Event) {
+func (s *Screen) PostEvent(event Event) {
+	s.context.Emit(event)
+}
+
+func (s *Screen) Do(task Task) error {
+	err := s.context.Do(task)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Sync ждёт, пока будут обработаны все события и задачи в очереди, включая порождённые ими.
+// Нельзя вызывать из обработчиков
+func (s *Screen) Sync() error {
+	idleChannel := make(chan struct{})
+	s.context.Post(func(*Context) {
+		s.idleWaiters = append(s.idleWaiters, idleChannel)
+	})
+
+	select {
+	case <-idleChannel:
+		return nil
+	case <-s.context.stopChannel:
+		return ErrScreenStopped
+	}
+}
+
+func (s *Screen) getEvents() {
 	for {
-		termboxEvent := termbox.PollEvent()
-		event := termboxEventToEvent(termboxEvent)
-		eventChannel <- event
+		event := s.backend.PollEvent()
+		if event == nil {
+			return
+		}
+
+		s.context.Emit(event)
+	}
+}
+
+func (s *Screen) handleMessages(uiContext *Context) {
+	for message, ok := s.context.messages.pop(); ok; message, ok = s.context.messages.pop() {
+		if message.task != nil {
+			message.task(uiContext)
+		} else {
+			s.dispatchEvent(message.event)
+		}
+
+		// после Kill оставшиеся сообщения не обрабатываются
+		if s.context.isKilled() {
+			return
+		}
+	}
+
+	s.notifyIdle()
+}
+
+// notifyIdle отпускает Sync, если очередь пуста и ни один обработчик не выполняется
+func (s *Screen) notifyIdle() {
+	if s.handlersRunning != 0 || s.context.messages.len() != 0 {
+		return
+	}
+
+	for _, idleChannel := range s.idleWaiters {
+		close(idleChannel)
+	}
+	s.idleWaiters = nil
+}
+
+func (s *Screen) dispatchEvent(event Event) {
+	// EventTick мог попасть в очередь до StopTimer
+	eventTick, ok := event.(*EventTick)
+	if ok && !s.context.timers.fire(eventTick.ID) {
+		return
+	}
+
+	switch s.dispatchMode {
+	case DispatchConcurrent:
+		s.handlersRunning++
+		go func() {
+			s.handleEvent(event)
+
+			// счётчик уменьшается в горутине Run после событий, которые успел отправить обработчик,
+			// поэтому Sync не увидит пустую очередь раньше них
+			s.context.Post(func(*Context) {
+				s.handlersRunning--
+			})
+		}()
+	default:
+		s.handleEvent(event)
 	}
 }
 
@@ -103,7 +230,7 @@ func (s *Screen) handleEvent(eventType Event) {
 		s.context.setViewSize(event.X, event.Y)
 	}
 
-	currentState := s.context.getCurrentState()
+	currentState := s.context.CurrentState()
 
 	handlers := s.getHandlers(currentState)
 	childContext := s.context.newChildContext()
@@ -129,21 +256,47 @@ func (s *Screen) handleEvent(eventType Event) {
 // init
 
 func (s *Screen) Init() error {
-	err := termbox.Init()
+	err := s.backend.Init()
 	if err != nil {
 		return err
 	}
 
-	termbox.SetOutputMode(termbox.OutputRGB)
+	if s.outputMode == OutputAuto {
+		s.outputMode = DetectOutputMode()
+	}
+
+	s.backend.SetOutputMode(s.outputMode)
+	s.context.renderer.outputMode = s.outputMode
+
+	if s.graphics == GraphicsAuto {
+		s.graphics = DetectGraphicsProtocol()
+	}
 
-	viewSizeX, viewSizeY := termbox.Size()
+	_, ok := s.backend.(RawWriter)
+	if !ok {
+		s.graphics = GraphicsNone
+	}
+
+	s.context.graphics.protocol = s.graphics
+
+	viewSizeX, viewSizeY := s.backend.Size()
 	s.context.setViewSize(viewSizeX, viewSizeY)
 
 	return nil
 }
 
+// OutputMode возвращает режим цвета, выбранный при Init
+func (s *Screen) OutputMode() OutputMode {
+	return s.outputMode
+}
+
+// GraphicsProtocol возвращает протокол картинок, выбранный при Init
+func (s *Screen) GraphicsProtocol() GraphicsProtocol {
+	return s.graphics
+}
+
 func (s *Screen) Close() {
-	termbox.Close()
+	s.backend.Close()
 }
 
 // util
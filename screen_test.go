@@ -0,0 +1,91 @@
+package gui_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gggallahad/gui"
+	"github.com/gggallahad/gui/guitest"
+)
+
+const (
+	injectedEvents int = 500
+)
+
+func injectSymbols(t *testing.T, dispatchMode gui.DispatchMode) []rune {
+	t.Helper()
+
+	h, err := guitest.New(10, 2, gui.ScreenConfig{DispatchMode: dispatchMode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	mutex := sync.Mutex{}
+	received := []rune{}
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		eventKey, ok := event.(*gui.EventKey)
+		if !ok {
+			return
+		}
+
+		// в DispatchConcurrent рисовать можно только в горутине Run
+		ctx.Do(func(uiContext *gui.Context) {
+			uiContext.SetCell(0, 0, gui.Cell{Symbol: eventKey.Symbol})
+		})
+
+		mutex.Lock()
+		received = append(received, eventKey.Symbol)
+		mutex.Unlock()
+	})
+
+	err = h.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// события отправляются без ожидания, пока Run уже разбирает очередь
+	for i := range injectedEvents {
+		h.Screen.PostEvent(&gui.EventKey{Symbol: rune('a' + i%26)})
+	}
+
+	err = h.Screen.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return slices.Clone(received)
+}
+
+func expectedSymbols() []rune {
+	symbols := make([]rune, injectedEvents)
+	for i := range symbols {
+		symbols[i] = rune('a' + i%26)
+	}
+
+	return symbols
+}
+
+func TestSerialDispatchKeepsOrder(t *testing.T) {
+	received := injectSymbols(t, gui.DispatchSerial)
+
+	if !slices.Equal(received, expectedSymbols()) {
+		t.Fatalf("events handled out of order or lost: got %d events %q", len(received), string(received))
+	}
+}
+
+func TestConcurrentDispatchHandlesAll(t *testing.T) {
+	received := injectSymbols(t, gui.DispatchConcurrent)
+
+	expected := expectedSymbols()
+	slices.Sort(received)
+	slices.Sort(expected)
+
+	if !slices.Equal(received, expected) {
+		t.Fatalf("got %d events, want %d", len(received), len(expected))
+	}
+}
@@ -0,0 +1,132 @@
+package gui
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strconv"
+)
+
+const (
+	// в одной полосе Sixel шесть строк точек, символ полосы - '?' плюс битовая маска столбца
+	sixelBandHeight int  = 6
+	sixelEmpty      byte = '?'
+)
+
+// EncodeSixel кодирует img последовательностью Sixel с точками 1:1. Цвета приводятся к 256-цветной палитре,
+// номера регистров выдаются в порядке первого появления цвета. Точки прозрачнее половины не рисуются
+func EncodeSixel(img image.Image) []byte {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// регистр цвета каждой точки, -1 - прозрачная точка
+	registers := make([]int, width*height)
+	registerColors := []Color{}
+	paletteRegisters := make(map[int]int)
+
+	for y := range height {
+		for x := range width {
+			pixel := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			if pixel.A < 128 {
+				registers[y*width+x] = -1
+				continue
+			}
+
+			paletteIndex := Color{R: int(pixel.R), G: int(pixel.G), B: int(pixel.B)}.PaletteIndex(Output256)
+
+			register, ok := paletteRegisters[paletteIndex]
+			if !ok {
+				register = len(registerColors)
+				paletteRegisters[paletteIndex] = register
+				registerColors = append(registerColors, ansiPalette[paletteIndex])
+			}
+
+			registers[y*width+x] = register
+		}
+	}
+
+	buffer := bytes.Buffer{}
+
+	// P2=1: незакрашенные точки остаются прозрачными
+	buffer.WriteString("\x1bP0;1;0q\"1;1;")
+	buffer.WriteString(strconv.Itoa(width))
+	buffer.WriteByte(';')
+	buffer.WriteString(strconv.Itoa(height))
+
+	for register, registerColor := range registerColors {
+		buffer.WriteByte('#')
+		buffer.WriteString(strconv.Itoa(register))
+		buffer.WriteString(";2;")
+		buffer.WriteString(strconv.Itoa(sixelPercent(registerColor.R)))
+		buffer.WriteByte(';')
+		buffer.WriteString(strconv.Itoa(sixelPercent(registerColor.G)))
+		buffer.WriteByte(';')
+		buffer.WriteString(strconv.Itoa(sixelPercent(registerColor.B)))
+	}
+
+	line := make([]byte, width)
+	for bandY := 0; bandY < height; bandY += sixelBandHeight {
+		if bandY > 0 {
+			buffer.WriteByte('-')
+		}
+
+		bandHeight := min(sixelBandHeight, height-bandY)
+
+		for register := range registerColors {
+			used := false
+			for x := range width {
+				var bits byte
+				for row := range bandHeight {
+					if registers[(bandY+row)*width+x] == register {
+						bits |= 1 << row
+					}
+				}
+
+				line[x] = sixelEmpty + bits
+				used = used || bits != 0
+			}
+
+			if !used {
+				continue
+			}
+
+			buffer.WriteByte('#')
+			buffer.WriteString(strconv.Itoa(register))
+			writeSixelLine(&buffer, bytes.TrimRight(line, string(sixelEmpty)))
+			buffer.WriteByte('$')
+		}
+	}
+
+	buffer.WriteString("\x1b\\")
+
+	return buffer.Bytes()
+}
+
+// writeSixelLine сжимает повторы: четыре и больше одинаковых символа записываются как !<число><символ>
+func writeSixelLine(buffer *bytes.Buffer, line []byte) {
+	for start := 0; start < len(line); {
+		end := start + 1
+		for end < len(line) && line[end] == line[start] {
+			end++
+		}
+
+		count := end - start
+		if count >= 4 {
+			buffer.WriteByte('!')
+			buffer.WriteString(strconv.Itoa(count))
+			buffer.WriteByte(line[start])
+		} else {
+			for range count {
+				buffer.WriteByte(line[start])
+			}
+		}
+
+		start = end
+	}
+}
+
+// sixelPercent переводит канал 0-255 в проценты, в которых Sixel задаёт цвета регистров
+func sixelPercent(channel int) int {
+	return (channel*100 + 127) / 255
+}
@@ -0,0 +1,58 @@
+package gui
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncodeSixel(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+
+	// две полосы: синие столбцы по краям в шесть точек и красный посередине в семь
+	stripes := image.NewNRGBA(image.Rect(0, 0, 3, 7))
+	for y := range 7 {
+		if y < 6 {
+			stripes.SetNRGBA(0, y, blue)
+			stripes.SetNRGBA(2, y, blue)
+		}
+		stripes.SetNRGBA(1, y, red)
+	}
+
+	line := image.NewNRGBA(image.Rect(0, 0, 5, 1))
+	for x := range 5 {
+		line.SetNRGBA(x, 0, red)
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{
+			name: "transparent",
+			img:  image.NewNRGBA(image.Rect(0, 0, 2, 1)),
+			want: "\x1bP0;1;0q\"1;1;2;1\x1b\\",
+		},
+		{
+			name: "two bands",
+			img:  stripes,
+			want: "\x1bP0;1;0q\"1;1;3;7#0;2;0;0;100#1;2;100;0;0#0~?~$#1?~$-#1?@$\x1b\\",
+		},
+		{
+			name: "run length",
+			img:  line,
+			want: "\x1bP0;1;0q\"1;1;5;1#0;2;100;0;0#0!5@$\x1b\\",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := string(EncodeSixel(test.img))
+			if got != test.want {
+				t.Fatalf("EncodeSixel\ngot  %q\nwant %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package gui
+
+import (
+	"os"
+
+	"github.com/nsf/termbox-go"
+)
+
+type (
+	TermboxBackend struct {
+		outputMode OutputMode
+
+		// терминал, в который пишет termbox: stdout может быть перенаправлен в файл
+		tty *os.File
+	}
+)
+
+func NewTermboxBackend() *TermboxBackend {
+	backend := TermboxBackend{
+		outputMode: OutputRGB,
+		tty:        nil,
+	}
+
+	return &backend
+}
+
+func (b *TermboxBackend) Init() error {
+	err := termbox.Init()
+	if err != nil {
+		return err
+	}
+
+	// без /dev/tty (например, в Windows) WriteRaw пишет в stdout
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err == nil {
+		b.tty = tty
+	}
+
+	return nil
+}
+
+func (b *TermboxBackend) Close() {
+	termbox.Close()
+
+	if b.tty != nil {
+		b.tty.Close()
+		b.tty = nil
+	}
+}
+
+func (b *TermboxBackend) Size() (int, int) {
+	return termbox.Size()
+}
+
+// termbox сам пропускает клетку после широкого символа, а графемные кластеры выводит только первой руной
+func (b *TermboxBackend) SetCell(x, y int, cell Cell) {
+	if cell.Symbol == ContinuationSymbol {
+		return
+	}
+
+	foregroundAttribute := b.colorToAttribute(cell.Foreground) | cell.Attributes.toAttribute()
+	backgroundAttribute := b.colorToAttribute(cell.Background)
+
+	termbox.SetCell(x, y, cell.Symbol, foregroundAttribute, backgroundAttribute)
+}
+
+func (b *TermboxBackend) Clear(cell Cell) error {
+	foregroundAttribute := b.colorToAttribute(cell.Foreground) | cell.Attributes.toAttribute()
+	backgroundAttribute := b.colorToAttribute(cell.Background)
+
+	err := termbox.Clear(foregroundAttribute, backgroundAttribute)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (b *TermboxBackend) Flush() error {
+	err := termbox.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Sync очищает терминал и выводит весь буфер termbox, а не только отличия от прошлого Flush
+func (b *TermboxBackend) Sync() error {
+	err := termbox.Sync()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SyncRows выводит строки заново в два Flush подряд: сначала клетки заменяются пробелами с переключённой жирностью,
+// которые отличаются от любой клетки кадра termbox, но сами не видны, затем клетки возвращаются
+func (b *TermboxBackend) SyncRows(y, height int) error {
+	width, screenHeight := termbox.Size()
+	start := min(max(y, 0), screenHeight) * width
+	end := min(max(y+height, 0), screenHeight) * width
+
+	cells := termbox.CellBuffer()
+	saved := make([]termbox.Cell, end-start)
+	copy(saved, cells[start:end])
+
+	for i := start; i < end; i++ {
+		cells[i] = termbox.Cell{Ch: ' ', Fg: cells[i].Fg ^ termbox.AttrBold, Bg: cells[i].Bg}
+	}
+
+	err := termbox.Flush()
+	if err != nil {
+		return err
+	}
+
+	copy(cells[start:end], saved)
+
+	err = termbox.Flush()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WriteRaw выводит data в терминал в обход termbox, поэтому вызывается после Flush
+func (b *TermboxBackend) WriteRaw(data []byte) error {
+	writer := os.Stdout
+	if b.tty != nil {
+		writer = b.tty
+	}
+
+	_, err := writer.Write(data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (b *TermboxBackend) PollEvent() Event {
+	for {
+		termboxEvent := termbox.PollEvent()
+		if termboxEvent.Type == termbox.EventInterrupt {
+			return nil
+		}
+
+		// события, которым нет соответствия (ошибки, raw), пропускаются
+		event := termboxEventToEvent(termboxEvent)
+		if event != nil {
+			return event
+		}
+	}
+}
+
+func (b *TermboxBackend) SetCursor(x, y int) {
+	termbox.SetCursor(x, y)
+}
+
+func (b *TermboxBackend) HideCursor() {
+	termbox.HideCursor()
+}
+
+func (b *TermboxBackend) SetOutputMode(outputMode OutputMode) {
+	b.outputMode = outputMode
+
+	termbox.SetOutputMode(outputMode.toTermboxOutputMode())
+}
+
+// util
+
+func (m OutputMode) toTermboxOutputMode() termbox.OutputMode {
+	switch m {
+	case Output256:
+		return termbox.Output256
+	case OutputRGB:
+		return termbox.OutputRGB
+	default:
+		return termbox.OutputNormal
+	}
+}
+
+// в режимах с палитрой termbox ждёт номер цвета, увеличенный на единицу: ноль занят ColorDefault
+func (b *TermboxBackend) colorToAttribute(color Color) termbox.Attribute {
+	if color == DefaultColor {
+		return termbox.ColorDefault
+	}
+
+	if b.outputMode == OutputRGB {
+		return color.toAttribute()
+	}
+
+	index := color.PaletteIndex(b.outputMode)
+	attribute := termbox.Attribute(index + 1)
+
+	return attribute
+}
+
+func (c *Color) toAttribute() termbox.Attribute {
+	if *c == DefaultColor {
+		return termbox.ColorDefault
+	}
+
+	attribute := termbox.RGBToAttribute(uint8(c.R), uint8(c.G), uint8(c.B))
+
+	return attribute
+}
+
+// termbox применяет флаги начертания только из foreground. Зачёркивания в termbox нет, флаг игнорируется
+func (a Attribute) toAttribute() termbox.Attribute {
+	var attribute termbox.Attribute
+
+	if a.Has(AttrBold) {
+		attribute |= termbox.AttrBold
+	}
+	if a.Has(AttrItalic) {
+		attribute |= termbox.AttrCursive
+	}
+	if a.Has(AttrUnderline) {
+		attribute |= termbox.AttrUnderline
+	}
+	if a.Has(AttrReverse) {
+		attribute |= termbox.AttrReverse
+	}
+	if a.Has(AttrBlink) {
+		attribute |= termbox.AttrBlink
+	}
+	if a.Has(AttrDim) {
+		attribute |= termbox.AttrDim
+	}
+
+	return attribute
+}
+
+// func (c *Color) fromAttribute(attribute termbox.Attribute) Color {
+// 	if attribute == termbox.ColorDefault {
+// 		color := DefaultColor
+
+// 		return color
+// 	}
+
+// 	r, g, b := termbox.AttributeToRGB(attribute)
+// 	color := Color{
+// 		R: int(r),
+// 		G: int(g),
+// 		B: int(b),
+// 	}
+
+// 	return color
+// }
+
+func termboxEventToEvent(termboxEvent termbox.Event) Event {
+	var event Event
+
+	switch termboxEvent.Type {
+	case termbox.EventKey:
+		eventKey := &EventKey{
+			Symbol:   termboxEvent.Ch,
+			Key:      KeyboardKey(termboxEvent.Key),
+			Modifier: Modifier(termboxEvent.Mod),
+		}
+		event = eventKey
+	case termbox.EventMouse:
+		eventMouse := &EventMouse{
+			X:   termboxEvent.MouseX,
+			Y:   termboxEvent.MouseY,
+			Key: MouseKey(termboxEvent.Key),
+		}
+		event = eventMouse
+	case termbox.EventResize:
+		eventResize := &EventResize{
+			X: termboxEvent.Width,
+			Y: termboxEvent.Height,
+		}
+		event = eventResize
+	}
+
+	return event
+}
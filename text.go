@@ -0,0 +1,78 @@
+package gui
+
+import (
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	// ContinuationSymbol занимает правую половину широкого символа. Бэкенды такие клетки не рисуют
+	ContinuationSymbol rune = -1
+)
+
+// TextWidth возвращает ширину text в клетках терминала с учётом широких символов и графемных кластеров
+func TextWidth(text string) int {
+	width := 0
+
+	state := -1
+	for len(text) > 0 {
+		var clusterWidth int
+		_, text, clusterWidth, state = uniseg.FirstGraphemeClusterInString(text, state)
+		width += clusterWidthToCells(clusterWidth)
+	}
+
+	return width
+}
+
+// textToCells разбивает text на графемные кластеры: первая руна кластера идёт в Symbol,
+// остальные в Combining. За широким кластером следует клетка с ContinuationSymbol
+func textToCells(text string, textCell Cell) []Cell {
+	cells := make([]Cell, 0, len(text))
+
+	state := -1
+	for len(text) > 0 {
+		var cluster string
+		var clusterWidth int
+		cluster, text, clusterWidth, state = uniseg.FirstGraphemeClusterInString(text, state)
+
+		symbol, combining := splitCluster(cluster)
+
+		textCell.Symbol = symbol
+		textCell.Combining = combining
+		cells = append(cells, textCell)
+
+		for range clusterWidthToCells(clusterWidth) - 1 {
+			continuationCell := textCell
+			continuationCell.Symbol = ContinuationSymbol
+			continuationCell.Combining = ""
+			cells = append(cells, continuationCell)
+		}
+	}
+
+	return cells
+}
+
+// isWideCell сообщает, занимает ли символ клетки две клетки терминала
+func isWideCell(cell Cell) bool {
+	if cell.Symbol <= 0 {
+		return false
+	}
+
+	return TextWidth(string(cell.Symbol)+cell.Combining) == 2
+}
+
+func splitCluster(cluster string) (rune, string) {
+	symbol, size := utf8.DecodeRuneInString(cluster)
+
+	return symbol, cluster[size:]
+}
+
+// кластер нулевой ширины (например, одиночный комбинирующий знак) всё равно занимает клетку
+func clusterWidthToCells(clusterWidth int) int {
+	if clusterWidth < 1 {
+		return 1
+	}
+
+	return clusterWidth
+}
@@ -0,0 +1,146 @@
+package gui
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type (
+	TimerID int
+
+	timer struct {
+		cancelFunc context.CancelFunc
+		// одноразовый таймер After снимается с учёта, когда его EventTick передаётся обработчикам
+		once bool
+	}
+
+	// timers - таймеры, которые ещё не остановлены. EventTick таймера, которого здесь нет, не обрабатывается
+	timers struct {
+		mutex  sync.Mutex
+		nextID TimerID
+		timers map[TimerID]timer
+	}
+)
+
+func newTimers() *timers {
+	registered := make(map[TimerID]timer)
+
+	t := timers{
+		nextID: 1,
+		timers: registered,
+	}
+
+	return &t
+}
+
+func (t *timers) add(cancelFunc context.CancelFunc, once bool) TimerID {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	id := t.nextID
+	t.nextID++
+
+	t.timers[id] = timer{
+		cancelFunc: cancelFunc,
+		once:       once,
+	}
+
+	return id
+}
+
+func (t *timers) remove(id TimerID) bool {
+	t.mutex.Lock()
+	registered, ok := t.timers[id]
+	delete(t.timers, id)
+	t.mutex.Unlock()
+
+	if ok {
+		registered.cancelFunc()
+	}
+
+	return ok
+}
+
+// fire проверяет в горутине Run, что таймер id не остановлен, прежде чем передать его EventTick обработчикам.
+// Одноразовый таймер при этом снимается с учёта. EventTick с чужим ID, например из PostEvent, не отбрасывается
+func (t *timers) fire(id TimerID) bool {
+	t.mutex.Lock()
+	if id <= 0 || id >= t.nextID {
+		t.mutex.Unlock()
+
+		return true
+	}
+
+	registered, ok := t.timers[id]
+	if ok && registered.once {
+		delete(t.timers, id)
+	}
+	t.mutex.Unlock()
+
+	if ok && registered.once {
+		registered.cancelFunc()
+	}
+
+	return ok
+}
+
+// таймеры
+
+// After отправляет одно EventTick через duration
+func (ctx *Context) After(duration time.Duration) TimerID {
+	timerContext, cancelFunc := context.WithCancel(ctx.rootContext)
+	id := ctx.timers.add(cancelFunc, true)
+
+	go func() {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+
+		select {
+		case <-timerContext.Done():
+		case tickTime := <-timer.C:
+			ctx.emitTick(id, tickTime)
+		}
+	}()
+
+	return id
+}
+
+// Every отправляет EventTick каждые interval, пока таймер не остановлен
+func (ctx *Context) Every(interval time.Duration) TimerID {
+	timerContext, cancelFunc := context.WithCancel(ctx.rootContext)
+	id := ctx.timers.add(cancelFunc, false)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-timerContext.Done():
+				return
+			case tickTime := <-ticker.C:
+				ctx.emitTick(id, tickTime)
+			}
+		}
+	}()
+
+	return id
+}
+
+// StopTimer останавливает таймер: после него EventTick таймера не придут, даже если уже стоят в очереди.
+// Возвращает false, если таймер уже сработал или был остановлен
+func (ctx *Context) StopTimer(id TimerID) bool {
+	ok := ctx.timers.remove(id)
+
+	return ok
+}
+
+func (ctx *Context) emitTick(id TimerID, tickTime time.Time) {
+	eventTick := &EventTick{
+		ID:   id,
+		Time: tickTime,
+	}
+
+	ctx.Emit(eventTick)
+}
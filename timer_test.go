@@ -0,0 +1,106 @@
+package gui_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gggallahad/gui"
+	"github.com/gggallahad/gui/guitest"
+)
+
+func TestStopTimerDropsQueuedTick(t *testing.T) {
+	h, err := guitest.New(10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ticks := atomic.Int32{}
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		_, ok := event.(*gui.EventTick)
+		if ok {
+			ticks.Add(1)
+		}
+	})
+
+	err = h.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := false
+	err = h.Screen.Do(func(ctx *gui.Context) {
+		id := ctx.After(time.Millisecond)
+
+		// горутина Run занята, поэтому EventTick успевает встать в очередь до StopTimer
+		time.Sleep(50 * time.Millisecond)
+
+		stopped = ctx.StopTimer(id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Screen.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !stopped {
+		t.Fatal("StopTimer returned false for a timer whose tick was not handled yet")
+	}
+	if ticks.Load() != 0 {
+		t.Fatalf("got %d ticks after StopTimer", ticks.Load())
+	}
+}
+
+func TestAfterFiresOnce(t *testing.T) {
+	h, err := guitest.New(10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ticks := make(chan gui.TimerID, 2)
+	h.Screen.BindHandlers(gui.NoState, func(ctx *gui.Context, event gui.Event) {
+		eventTick, ok := event.(*gui.EventTick)
+		if ok {
+			ticks <- eventTick.ID
+		}
+	})
+
+	err = h.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id gui.TimerID
+	err = h.Screen.Do(func(ctx *gui.Context) {
+		id = ctx.After(time.Millisecond)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tickID := <-ticks:
+		if tickID != id {
+			t.Fatalf("got tick of timer %d, want %d", tickID, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tick was not delivered")
+	}
+
+	stopped := true
+	err = h.Screen.Do(func(ctx *gui.Context) {
+		stopped = ctx.StopTimer(id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stopped {
+		t.Fatal("StopTimer returned true for a fired timer")
+	}
+}
@@ -1,7 +1,92 @@
 package gui
 
-func growSlice[Type any](slice []Type, newLen int) []Type {
-	newSlice := make([]Type, newLen)
-	copy(newSlice, slice)
-	return newSlice[:len(slice)]
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+type (
+	// неограниченная очередь: запись никогда не блокируется, signal сообщает читателю о новых элементах
+	queue[Type any] struct {
+		mutex  sync.Mutex
+		items  []Type
+		signal chan struct{}
+	}
+)
+
+func newCells(count int, cell Cell) []Cell {
+	cells := make([]Cell, count)
+	for i := range cells {
+		cells[i] = cell
+	}
+
+	return cells
+}
+
+// goroutineID возвращает номер текущей горутины из первой строки её стека: "goroutine N [running]:".
+// Номера начинаются с 1, поэтому 0 не совпадает ни с одной горутиной
+func goroutineID() uint64 {
+	buffer := make([]byte, 64)
+	buffer = buffer[:runtime.Stack(buffer, false)]
+	buffer = bytes.TrimPrefix(buffer, []byte("goroutine "))
+
+	end := bytes.IndexByte(buffer, ' ')
+	if end < 0 {
+		return 0
+	}
+
+	id, err := strconv.ParseUint(string(buffer[:end]), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+func newQueue[Type any]() *queue[Type] {
+	signal := make(chan struct{}, 1)
+
+	q := queue[Type]{
+		items:  nil,
+		signal: signal,
+	}
+
+	return &q
+}
+
+func (q *queue[Type]) push(item Type) {
+	q.mutex.Lock()
+	q.items = append(q.items, item)
+	q.mutex.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (q *queue[Type]) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.items)
+}
+
+func (q *queue[Type]) pop() (Type, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var item Type
+	if len(q.items) == 0 {
+		return item, false
+	}
+
+	var empty Type
+	item = q.items[0]
+	q.items[0] = empty
+	q.items = q.items[1:]
+
+	return item, true
 }
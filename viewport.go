@@ -0,0 +1,171 @@
+package gui
+
+import "slices"
+
+const (
+	// MainViewport - видимая область, которой управляют SetViewPosition и ViewSize.
+	// По умолчанию занимает весь экран
+	MainViewport string = "main"
+)
+
+type (
+	// viewport показывает мир с камеры (cameraX, cameraY) в прямоугольнике экрана rect
+	viewport struct {
+		name    string
+		rect    Rect
+		cameraX *int
+		cameraY *int
+		// прямоугольник следует за размером экрана, пока его не задали явно
+		fullScreen bool
+	}
+
+	// видимые области рисуются в порядке добавления, более поздние поверх ранних
+	viewports struct {
+		list []*viewport
+	}
+)
+
+func newViewport(name string, rect Rect, cameraX, cameraY int) *viewport {
+	v := viewport{
+		name:       name,
+		rect:       rect,
+		cameraX:    &cameraX,
+		cameraY:    &cameraY,
+		fullScreen: false,
+	}
+
+	return &v
+}
+
+func newViewports(mainCameraX, mainCameraY *int) *viewports {
+	mainViewport := viewport{
+		name:       MainViewport,
+		rect:       Rect{},
+		cameraX:    mainCameraX,
+		cameraY:    mainCameraY,
+		fullScreen: true,
+	}
+
+	v := viewports{
+		list: []*viewport{&mainViewport},
+	}
+
+	return &v
+}
+
+func (v *viewports) find(name string) (*viewport, bool) {
+	for i := range v.list {
+		if v.list[i].name == name {
+			return v.list[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func (v *viewports) main() *viewport {
+	mainViewport, _ := v.find(MainViewport)
+
+	return mainViewport
+}
+
+func (v *viewports) resize(width, height int) {
+	for i := range v.list {
+		if v.list[i].fullScreen {
+			v.list[i].rect = NewRect(0, 0, width, height)
+		}
+	}
+}
+
+// toScreen переводит клетку мира в клетку экрана этой видимой области
+func (v *viewport) toScreen(x, y int) (int, int) {
+	return x - *v.cameraX + v.rect.X, y - *v.cameraY + v.rect.Y
+}
+
+// viewports
+
+// AddViewport добавляет видимую область, которая показывает мир с камеры (cameraX, cameraY)
+// в прямоугольнике экрана rect поверх ранее добавленных
+func (ctx *Context) AddViewport(name string, rect Rect, cameraX, cameraY int) error {
+	_, ok := ctx.viewports.find(name)
+	if ok {
+		return ErrViewportExists
+	}
+
+	addedViewport := newViewport(name, rect, cameraX, cameraY)
+	ctx.viewports.list = append(ctx.viewports.list, addedViewport)
+
+	return nil
+}
+
+func (ctx *Context) RemoveViewport(name string) error {
+	if name == MainViewport {
+		return ErrMainViewport
+	}
+
+	_, ok := ctx.viewports.find(name)
+	if !ok {
+		return ErrViewportNotFound
+	}
+
+	ctx.viewports.list = slices.DeleteFunc(ctx.viewports.list, func(current *viewport) bool {
+		return current.name == name
+	})
+
+	return nil
+}
+
+// SetViewportRect задаёт прямоугольник экрана. После этого MainViewport перестаёт следовать за размером экрана
+func (ctx *Context) SetViewportRect(name string, rect Rect) error {
+	foundViewport, ok := ctx.viewports.find(name)
+	if !ok {
+		return ErrViewportNotFound
+	}
+
+	foundViewport.rect = rect
+	foundViewport.fullScreen = false
+
+	return nil
+}
+
+// SetViewportCamera задаёт клетку мира, которая видна в левом верхнем углу области.
+// Для MainViewport то же самое, что SetViewPosition
+func (ctx *Context) SetViewportCamera(name string, cameraX, cameraY int) error {
+	foundViewport, ok := ctx.viewports.find(name)
+	if !ok {
+		return ErrViewportNotFound
+	}
+
+	*foundViewport.cameraX = cameraX
+	*foundViewport.cameraY = cameraY
+
+	return nil
+}
+
+func (ctx *Context) ViewportRect(name string) (Rect, error) {
+	foundViewport, ok := ctx.viewports.find(name)
+	if !ok {
+		return Rect{}, ErrViewportNotFound
+	}
+
+	return foundViewport.rect, nil
+}
+
+func (ctx *Context) ViewportCamera(name string) (int, int, error) {
+	foundViewport, ok := ctx.viewports.find(name)
+	if !ok {
+		return 0, 0, ErrViewportNotFound
+	}
+
+	return *foundViewport.cameraX, *foundViewport.cameraY, nil
+}
+
+// Viewports возвращает имена видимых областей в порядке отрисовки
+func (ctx *Context) Viewports() []string {
+	names := make([]string, 0, len(ctx.viewports.list))
+	for i := range ctx.viewports.list {
+		names = append(names, ctx.viewports.list[i].name)
+	}
+
+	return names
+}